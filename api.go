@@ -4,41 +4,99 @@ package spatially
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
 
-//
+// API is an authenticated connection to Spatially. It prepares requests with the account's token.
 type API interface {
 	PrepareRequest(req *http.Request)
 	Error(response []byte) (err error)
 }
 
+// ClientProvider is implemented by the APIs which send their requests through a configured Client, as
+// the APIs returned by NewAPI do. Requests of other APIs are sent with a default Client.
+type ClientProvider interface {
+	Client() *Client
+}
+
+var defaultClient = NewClient()
+
+// clientOf returns the Client requests of the API are sent through
+func clientOf(db API) *Client {
+	if provider, ok := db.(ClientProvider); ok {
+		if client := provider.Client(); client != nil {
+			return client
+		}
+	}
+	return defaultClient
+}
+
 // SpatialConstraint is an object used to describe and boundary and intersection type from which
 // to query features with
 type SpatialConstraint struct {
-	WKT    string                `json:"wkt"`
+	WKT string `json:"wkt"`
+	// Radius in meters buffers the WKT shape, e.g. to query the features around a point
 	Radius float64               `json:"radius"`
 	Type   SpatialConstraintType `json:"type"`
+	// MinDistance and MaxDistance are the range of distances in meters from the shape of the features
+	// SpatialConstraintDistance selects. A MaxDistance of 0 has no maximum.
+	MinDistance float64 `json:"minDistance,omitempty"`
+	MaxDistance float64 `json:"maxDistance,omitempty"`
 }
 
-// SpatialConstraintType is the type of spatial intersection to do on features
+// SpatialConstraintType is the type of spatial intersection to do on features. It's sent to the API by
+// name, e.g. "within".
 type SpatialConstraintType int
 
 const (
 	// SpatialConstraintIntersect is a SpatialContraintType that only selects features that intersect
 	// with the given boundary
 	SpatialConstraintIntersect SpatialConstraintType = iota
+	// SpatialConstraintWithin only selects features that are within the given boundary, e.g. the stores in
+	// an ATA
+	SpatialConstraintWithin
+	// SpatialConstraintContains only selects features that contain the given boundary, e.g. the ATAs
+	// around a store
+	SpatialConstraintContains
+	// SpatialConstraintDisjoint only selects features that don't intersect with the given boundary
+	SpatialConstraintDisjoint
+	// SpatialConstraintDistance only selects features between the MinDistance and MaxDistance of the
+	// given boundary
+	SpatialConstraintDistance
 )
 
 // SpatiallyAPI - Spatially's API URL
 const SpatiallyAPI = "https://api.spatially.com"
 
 type api struct {
-	Token string
+	Token       string
+	client      *Client
+	credentials CredentialProvider
+	mutex       sync.RWMutex
+}
+
+// CredentialProvider supplies the api code & key that are exchanged for a token. It is asked again
+// every time the token has to be refreshed, so it can return rotated credentials.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (apiCode, apiKey string, err error)
+}
+
+// StaticCredentials is a CredentialProvider that always returns the same api code & key
+type StaticCredentials struct {
+	Code string
+	Key  string
+}
+
+// Credentials returns the static api code & key
+func (c StaticCredentials) Credentials(ctx context.Context) (string, string, error) {
+	return c.Code, c.Key, nil
 }
 
 type gatewayRequest struct {
@@ -51,49 +109,145 @@ type gatewayResponse struct {
 }
 
 // New created a new instance of the Spatially API. The parameters are the api code & key
-// provided by Spatially. It generates a token with the API.
-func NewAPI(apiCode, apiKey string) (API, error) {
+// provided by Spatially. It generates a token with the API. Client options can be given to change
+// where and how requests are sent.
+func NewAPI(apiCode, apiKey string, options ...ClientOption) (API, error) {
+	return NewAPIContext(context.Background(), apiCode, apiKey, options...)
+}
+
+// NewAPIContext is NewAPI with a context used to cancel the request or set its deadline
+func NewAPIContext(ctx context.Context, apiCode, apiKey string, options ...ClientOption) (API, error) {
+	return NewAPIWithCredentialsContext(ctx, StaticCredentials{Code: apiCode, Key: apiKey}, options...)
+}
+
+// NewAPIWithCredentials creates a new instance of the Spatially API that asks the provider for the api
+// code & key. When a request is rejected because the token expired, the token is generated again
+// and the request is retried once.
+func NewAPIWithCredentials(credentials CredentialProvider, options ...ClientOption) (API, error) {
+	return NewAPIWithCredentialsContext(context.Background(), credentials, options...)
+}
+
+// NewAPIWithCredentialsContext is NewAPIWithCredentials with a context used to cancel the request or set its deadline
+func NewAPIWithCredentialsContext(ctx context.Context, credentials CredentialProvider, options ...ClientOption) (API, error) {
+	s := &api{
+		client:      NewClient(options...),
+		credentials: credentials,
+	}
+	token, err := s.generateToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.Token = token
+	return s, nil
+}
+
+// generateToken exchanges the provider's api code & key for a token
+func (s *api) generateToken(ctx context.Context) (string, error) {
+	apiCode, apiKey, err := s.credentials.Credentials(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "spatialdb gateway credentials")
+	}
 	request := gatewayRequest{
 		Code: apiCode,
 		Key:  apiKey,
 	}
 	j, err := json.Marshal(request)
 	if err != nil {
-		return nil, errors.Wrap(err, "spatialdb json marshal gateway request")
+		return "", errors.Wrap(err, "spatialdb json marshal gateway request")
 	}
 	body := bytes.NewReader(j)
-	resp, err := http.Post(SpatiallyAPI+"/gateway/client", "application/json", body)
+	gateway, err := s.client.NewRequestContext(ctx, "POST", "/gateway/client", body)
+	if err != nil {
+		return "", errors.Wrap(err, "spatialdb prepare gateway request")
+	}
+	gateway.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(idempotent(gateway))
 	if err != nil {
-		return nil, errors.Wrap(err, "spatialdb gateway request")
+		return "", errors.Wrap(err, "spatialdb gateway request")
 	}
 	defer resp.Body.Close()
 	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, errors.Wrap(err, "spatialdb read gateway request response body")
+		return "", errors.Wrap(err, "spatialdb read gateway request response body")
+	}
+	if resp.StatusCode != 200 {
+		return "", newAPIError(gateway, resp, responseBody)
 	}
 	var response gatewayResponse
 	if err := json.Unmarshal(responseBody, &response); err != nil {
-		return nil, errors.Wrap(err, "spatialdb json unmarshal gateway request")
+		return "", errors.Wrap(err, "spatialdb json unmarshal gateway request")
 	}
 	if len(response.Token) == 0 {
-		return nil, errors.New("SpatialDB was not able to generate a valid token")
+		return "", errors.New("SpatialDB was not able to generate a valid token")
+	}
+	return response.Token, nil
+}
+
+// refreshToken generates a new token unless another request already replaced the rejected one
+func (s *api) refreshToken(ctx context.Context, rejected string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Token != rejected {
+		return nil
 	}
-	return &api{Token: response.Token}, nil
+	token, err := s.generateToken(ctx)
+	if err != nil {
+		return err
+	}
+	s.Token = token
+	return nil
+}
+
+// Client returns the Client the API's requests are sent through
+func (s *api) Client() *Client {
+	return s.client
 }
 
 func (s *api) PrepareRequest(request *http.Request) {
+	s.mutex.RLock()
+	token := s.Token
+	s.mutex.RUnlock()
 	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Authorization", "Bearer "+s.Token)
+	request.Header.Set("Authorization", "Bearer "+token)
+}
+
+type tokenRefresher interface {
+	refreshToken(ctx context.Context, rejected string) error
 }
 
-type requestError struct {
-	Message string `json:"message"`
+// send prepares the request and sends it through the API's client. If the token is rejected and
+// the API is able to refresh it, the request is sent once more with the new token.
+func send(db API, request *http.Request) (*http.Response, error) {
+	db.PrepareRequest(request)
+	resp, err := clientOf(db).Do(request)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	refresher, canRefresh := db.(tokenRefresher)
+	if !canRefresh || (request.Body != nil && request.GetBody == nil) {
+		return resp, nil
+	}
+	responseBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	rejected := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if err := refresher.refreshToken(request.Context(), rejected); err != nil {
+		return nil, &RefreshError{APIError: newAPIError(request, resp, responseBody), Err: err}
+	}
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		if retry.Body, err = request.GetBody(); err != nil {
+			return nil, errors.Wrap(err, "rewind request body")
+		}
+	}
+	db.PrepareRequest(retry)
+	return clientOf(db).Do(retry)
 }
 
+// Error creates an APIError from an error response body. The status code and endpoint are unknown,
+// prefer the errors returned by the API calls which carry them.
 func (s *api) Error(responseBody []byte) error {
-	var err requestError
-	if err := json.Unmarshal(responseBody, &err); err != nil {
-		return errors.Wrap(errors.New(string(responseBody)), "unable to parse error body")
+	return &APIError{
+		Message: errorMessage(responseBody),
+		Body:    responseBody,
 	}
-	return errors.New(err.Message)
 }
@@ -1,11 +1,17 @@
 package spatially
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/pkg/errors"
 	"gopkg.in/jarcoal/httpmock.v1"
 )
 
@@ -42,3 +48,134 @@ func mockGatewayEndpoint(t *testing.T) {
 		return httpmock.NewJsonResponse(200, gatewayResponse{"authToken"})
 	})
 }
+
+type rotatingCredentials struct {
+	calls int32
+}
+
+func (c *rotatingCredentials) Credentials(ctx context.Context) (string, string, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return applicationCode, applicationKey, nil
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var tokens, layerRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/gateway/client":
+			token := atomic.AddInt32(&tokens, 1)
+			json.NewEncoder(w).Encode(gatewayResponse{fmt.Sprintf("token%d", token)})
+		case "/spatialdb/layer":
+			atomic.AddInt32(&layerRequests, 1)
+			if req.Header.Get("Authorization") != "Bearer token2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(requestError{"token expired"})
+				return
+			}
+			var request createLayerRequest
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			json.NewEncoder(w).Encode(Layer{ID: "1", Name: request.Name})
+		}
+	}))
+	defer server.Close()
+	credentials := &rotatingCredentials{}
+	sdb, err := NewAPIWithCredentials(credentials, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := NewLayer()
+	if err := layer.Create(sdb, "layer1"); err != nil {
+		t.Fatal(err)
+	}
+	if layer.Name != "layer1" {
+		t.Error("The retried request should send the original body, got layer name", layer.Name)
+	}
+	if layerRequests != 2 {
+		t.Error("Expected the request to be retried once, got", layerRequests, "requests")
+	}
+	if credentials.calls != 2 {
+		t.Error("Expected the credentials to be requested twice, got", credentials.calls)
+	}
+}
+
+func TestTokenRefreshConcurrent(t *testing.T) {
+	var tokens int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/gateway/client":
+			token := atomic.AddInt32(&tokens, 1)
+			json.NewEncoder(w).Encode(gatewayResponse{fmt.Sprintf("token%d", token)})
+		case "/spatialdb/layers":
+			if req.Header.Get("Authorization") == "Bearer token1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(Layers{})
+		}
+	}))
+	defer server.Close()
+	sdb, err := NewAPI(applicationCode, applicationKey, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			layers := NewLayers()
+			if err := layers.Get(sdb); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if tokens != 2 {
+		t.Error("Expected a single token refresh, got", tokens-1)
+	}
+}
+
+func TestTokenRefreshUnauthorized(t *testing.T) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(requestError{"invalid token"})
+	})
+	defer server.Close()
+	layers := NewLayers()
+	if err := layers.Get(sdb); err == nil {
+		t.Error("Expected an error when the refreshed token is rejected too")
+	}
+}
+
+func TestTokenRefreshFails(t *testing.T) {
+	var tokens int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/gateway/client":
+			if atomic.AddInt32(&tokens, 1) > 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(gatewayResponse{"token1"})
+		case "/spatialdb/layers":
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(requestError{"token expired"})
+		}
+	}))
+	defer server.Close()
+	sdb, err := NewAPI(applicationCode, applicationKey, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := NewLayers()
+	err = layers.Get(sdb)
+	if !IsUnauthorized(err) {
+		t.Fatal("Expected the rejected request's error, got", err)
+	}
+	refreshErr, ok := errors.Cause(err).(*RefreshError)
+	if !ok || refreshErr.Message != "token expired" || !hasStatusCode(refreshErr.Err, http.StatusServiceUnavailable) {
+		t.Errorf("Expected the refresh error to be attached, got %v", err)
+	}
+}
@@ -2,9 +2,9 @@ package spatially
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
-	"net/http"
 
 	"github.com/Spatially/go-geometry"
 	"github.com/pkg/errors"
@@ -80,6 +80,11 @@ type ataResponse struct {
 
 //
 func NewATA(api API, locationWKT string, options *ATAOptions) (ata *ATA, err error) {
+	return NewATAContext(context.Background(), api, locationWKT, options)
+}
+
+// NewATAContext is NewATA with a context used to cancel the request or set its deadline
+func NewATAContext(ctx context.Context, api API, locationWKT string, options *ATAOptions) (ata *ATA, err error) {
 	requestBody := &ataRequest{
 		PointWKT: locationWKT,
 		AreaType: "ATA",
@@ -106,13 +111,11 @@ func NewATA(api API, locationWKT string, options *ATAOptions) (ata *ATA, err err
 		return nil, errors.Wrap(err, "request to json")
 	}
 	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/ads/science/ata", body)
+	request, err := clientOf(api).NewRequestContext(ctx, "POST", "/ads/science/ata", body)
 	if err != nil {
 		return nil, errors.Wrap(err, "ata request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, idempotent(request))
 	if err != nil {
 		return nil, errors.Wrap(err, "ata request do")
 	}
@@ -121,6 +124,9 @@ func NewATA(api API, locationWKT string, options *ATAOptions) (ata *ATA, err err
 	if err != nil {
 		return nil, errors.Wrap(err, "read ata request response body")
 	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(request, resp, responseBody)
+	}
 	var response ataResponse
 	if err := json.Unmarshal(responseBody, &response); err != nil {
 		return nil, errors.Wrap(err, "json unmarshal ata request")
@@ -1,6 +1,7 @@
 package spatially
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"log"
@@ -257,3 +258,18 @@ func TestNewATAGeofence(t *testing.T) {
 	}
 	log.Printf("%+v", *ata)
 }
+
+func TestNewATAContextCanceled(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	api, err := NewAPI(applicationCode, applicationKey)
+	if err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewATAContext(ctx, api, "POINT(-71.064156780428 42.35862883483673)", nil); err == nil {
+		t.Error("Expected an error for a canceled context")
+	}
+}
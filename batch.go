@@ -0,0 +1,352 @@
+package spatially
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBatchSize is the number of features sent in each request of a batch when BatchOptions sets none
+	DefaultBatchSize = 500
+	// DefaultBatchConcurrency is the number of requests of a batch sent at the same time when BatchOptions
+	// sets none
+	DefaultBatchConcurrency = 4
+)
+
+// BatchOptions sets how a batch of features is split into requests and how many are sent at the same
+// time. Requests are also subject to the client's rate and in flight limits for EndpointSpatialDB.
+type BatchOptions struct {
+	// Size is the number of features sent in each request, DefaultBatchSize when it's 0
+	Size int
+	// Concurrency is the number of requests sent at the same time, DefaultBatchConcurrency when it's 0
+	Concurrency int
+	// DryRun has Spatially report what the batch would do without doing it
+	DryRun bool
+}
+
+// BatchResult is the outcome of a batch operation for one feature
+type BatchResult struct {
+	// ID is the ID of the feature, as created by Spatially for CreateBatch
+	ID string
+	// Err is why the feature failed, nil when it succeeded
+	Err error
+}
+
+// BatchReport has the result of a batch operation for each feature, in the order of the features
+type BatchReport struct {
+	Results   []BatchResult
+	Succeeded int
+	Failed    int
+}
+
+// BatchError is returned with the BatchReport when some features of a batch failed
+type BatchError struct {
+	Failed int
+	Total  int
+	// First is the error of the first feature which failed
+	First error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d features failed, first: %v", e.Failed, e.Total, e.First)
+}
+
+// runBatch splits n features into chunks run by do, concurrently as set by options. do fills in the
+// results of the features from and to of its chunk.
+func runBatch(ctx context.Context, n int, options *BatchOptions, do func(ctx context.Context, from, to int, results []BatchResult)) (*BatchReport, error) {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+	size, concurrency := options.Size, options.Concurrency
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	report := &BatchReport{Results: make([]BatchResult, n)}
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for from := range chunks {
+				to := from + size
+				if to > n {
+					to = n
+				}
+				do(ctx, from, to, report.Results[from:to])
+			}
+		}()
+	}
+	for from := 0; from < n; from += size {
+		chunks <- from
+	}
+	close(chunks)
+	wg.Wait()
+
+	var first error
+	for _, result := range report.Results {
+		if result.Err == nil {
+			report.Succeeded++
+			continue
+		}
+		if first == nil {
+			first = result.Err
+		}
+		report.Failed++
+	}
+	if report.Failed > 0 {
+		return report, &BatchError{Failed: report.Failed, Total: n, First: first}
+	}
+	return report, nil
+}
+
+// sendBatch sends a batch request and reads the result of each of count features from the response,
+// and the features it has. Features the server rejects get an APIError with the server's message.
+func sendBatch(ctx context.Context, db API, method, path string, requestBody interface{}, count int) ([]BatchResult, []*geojson.Feature, error) {
+	j, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "json marshal request body")
+	}
+	request, err := clientOf(db).NewRequestContext(ctx, method, path, bytes.NewReader(j))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "prepare http request")
+	}
+	resp, err := send(db, request)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "http "+strings.ToLower(method))
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read response body")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, newAPIError(request, resp, responseBody)
+	}
+	var response []batchResponseResult
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, nil, errors.Wrap(err, "parse response body json")
+	}
+	if len(response) != count {
+		return nil, nil, fmt.Errorf("got %d results for %d features", len(response), count)
+	}
+	results := make([]BatchResult, count)
+	features := make([]*geojson.Feature, count)
+	for i, r := range response {
+		results[i].ID = r.ID
+		features[i] = r.Feature
+		if r.Error != "" {
+			results[i].Err = &APIError{
+				StatusCode: resp.StatusCode,
+				Method:     request.Method,
+				Endpoint:   request.URL.Path,
+				Message:    r.Error,
+			}
+		}
+	}
+	return results, features, nil
+}
+
+type batchResponseResult struct {
+	ID      string           `json:"id"`
+	Error   string           `json:"error,omitempty"`
+	Feature *geojson.Feature `json:"feature,omitempty"`
+}
+
+type createBatchRequest struct {
+	LayerID  string             `json:"layer"`
+	Features []*geojson.Feature `json:"features"`
+	DryRun   bool               `json:"dryRun,omitempty"`
+}
+
+// CreateBatch creates the features in the layer, sending them in chunks of BatchOptions.Size features
+// with up to BatchOptions.Concurrency requests at the same time. Each created feature is updated with
+// the ID Spatially gave it. The report has the result of each feature, in order, and a *BatchError is
+// returned with it when some failed. Geometries are validated or repaired first when the client is
+// configured WithGeometryValidation, and the features with an invalid geometry fail without being sent.
+// Repaired geometries are only sent, the features keep theirs.
+// With BatchOptions.DryRun the features are checked by Spatially but not created, and keep their IDs.
+func (f Features) CreateBatch(db API, layerID string, options *BatchOptions) (*BatchReport, error) {
+	return f.CreateBatchContext(context.Background(), db, layerID, options)
+}
+
+// CreateBatchContext is CreateBatch with a context used to cancel the requests or set their deadline
+func (f Features) CreateBatchContext(ctx context.Context, db API, layerID string, options *BatchOptions) (*BatchReport, error) {
+	return runBatch(ctx, len(f), options, func(ctx context.Context, from, to int, results []BatchResult) {
+		requestBody := createBatchRequest{LayerID: layerID, DryRun: options != nil && options.DryRun}
+		var sent []int
+		for i, feature := range f[from:to] {
+			if feature == nil || feature.Feature == nil {
+				results[i].Err = fmt.Errorf("create feature: nil feature")
+				continue
+			}
+			geometry, err := clientOf(db).prepareGeometry(feature.Geometry)
+			if err != nil {
+				results[i].Err = errors.Wrap(err, "create feature geometry")
+				continue
+			}
+			sentFeature := *feature.Feature
+			sentFeature.Geometry = geometry
+			requestBody.Features = append(requestBody.Features, &sentFeature)
+			sent = append(sent, i)
+		}
+		if len(sent) == 0 {
+			return
+		}
+		created, _, err := sendBatch(ctx, db, "POST", "/spatialdb/features/batch", requestBody, len(sent))
+		for j, i := range sent {
+			if err != nil {
+				results[i].Err = errors.Wrap(err, "create features")
+				continue
+			}
+			results[i] = created[j]
+			if results[i].Err == nil && !requestBody.DryRun {
+				f[from+i].ID = results[i].ID
+			}
+		}
+	})
+}
+
+type updateBatchRequest struct {
+	Features []updateBatchFeature `json:"features"`
+	DryRun   bool                 `json:"dryRun,omitempty"`
+}
+
+type updateBatchFeature struct {
+	ID         string                 `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// UpdateBatch updates the properties of features by ID, in chunks and concurrently as set by options, and
+// updates the slice receiver with the updated features. The report has the result of each feature in the
+// order of their IDs, and a *BatchError is returned with it when some failed. With BatchOptions.DryRun
+// nothing is updated and the receiver has the features as they would be.
+func (f *Features) UpdateBatch(db API, properties map[string]map[string]interface{}, options *BatchOptions) (*BatchReport, error) {
+	return f.UpdateBatchContext(context.Background(), db, properties, options)
+}
+
+// UpdateBatchContext is UpdateBatch with a context used to cancel the requests or set their deadline
+func (f *Features) UpdateBatchContext(ctx context.Context, db API, properties map[string]map[string]interface{}, options *BatchOptions) (*BatchReport, error) {
+	ids := make([]string, 0, len(properties))
+	for id := range properties {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	updated := make([]*geojson.Feature, len(ids))
+	report, err := runBatch(ctx, len(ids), options, func(ctx context.Context, from, to int, results []BatchResult) {
+		requestBody := updateBatchRequest{DryRun: options != nil && options.DryRun}
+		for _, id := range ids[from:to] {
+			requestBody.Features = append(requestBody.Features, updateBatchFeature{ID: id, Properties: properties[id]})
+		}
+		responseResults, features, err := sendBatch(ctx, db, "PUT", "/spatialdb/features/batch", requestBody, to-from)
+		fillBatchResults(results, updated[from:to], ids[from:to], responseResults, features, errors.Wrap(err, "update features"))
+	})
+	*f = affectedFeatures(updated)
+	return report, err
+}
+
+type deleteBatchRequest struct {
+	IDs    []string `json:"ids"`
+	DryRun bool     `json:"dryRun,omitempty"`
+}
+
+// DeleteBatch deletes features by ID, in chunks and concurrently as set by options, decreasing the feature
+// counts of their layers, and updates the slice receiver with the deleted features. The report has the
+// result of each feature in the order of ids, and a *BatchError is returned with it when some failed.
+// With BatchOptions.DryRun nothing is deleted and the receiver has the features which would be.
+func (f *Features) DeleteBatch(db API, ids []string, options *BatchOptions) (*BatchReport, error) {
+	return f.DeleteBatchContext(context.Background(), db, ids, options)
+}
+
+// DeleteBatchContext is DeleteBatch with a context used to cancel the requests or set their deadline
+func (f *Features) DeleteBatchContext(ctx context.Context, db API, ids []string, options *BatchOptions) (*BatchReport, error) {
+	deleted := make([]*geojson.Feature, len(ids))
+	report, err := runBatch(ctx, len(ids), options, func(ctx context.Context, from, to int, results []BatchResult) {
+		requestBody := deleteBatchRequest{IDs: ids[from:to], DryRun: options != nil && options.DryRun}
+		responseResults, features, err := sendBatch(ctx, db, "DELETE", "/spatialdb/features/batch", requestBody, to-from)
+		fillBatchResults(results, deleted[from:to], ids[from:to], responseResults, features, errors.Wrap(err, "delete features"))
+	})
+	*f = affectedFeatures(deleted)
+	return report, err
+}
+
+// DeleteBySpatialConstraint deletes the features of a layer which satisfy the spatial constraint and the
+// filters, see DeleteBatch. The IDs of the features are collected first from every page of the features,
+// with IterateFeatures, and with BatchOptions.DryRun the receiver has the features which would be deleted.
+func (f *Features) DeleteBySpatialConstraint(db API, layerID string, spatialConstraint *SpatialConstraint, options *BatchOptions, filters ...*Filter) (*BatchReport, error) {
+	return f.DeleteBySpatialConstraintContext(context.Background(), db, layerID, spatialConstraint, options, filters...)
+}
+
+// DeleteBySpatialConstraintContext is DeleteBySpatialConstraint with a context used to cancel the requests
+// or set their deadline
+func (f *Features) DeleteBySpatialConstraintContext(ctx context.Context, db API, layerID string, spatialConstraint *SpatialConstraint, options *BatchOptions, filters ...*Filter) (*BatchReport, error) {
+	it := IterateFeaturesContext(ctx, db, layerID, spatialConstraint, nil, filters...)
+	defer it.Close()
+	var ids []string
+	for {
+		feature, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "delete features by spatial constraint")
+		}
+		if feature == nil || feature.Feature == nil || feature.ID == nil {
+			continue
+		}
+		ids = append(ids, featureID(feature.ID))
+	}
+	return f.DeleteBatchContext(ctx, db, ids, options)
+}
+
+// featureID returns the ID of a feature as sent to the API. IDs decoded from JSON numbers are float64,
+// which are written without an exponent so that large IDs stay whole.
+func featureID(id interface{}) string {
+	switch id := id.(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+	return fmt.Sprint(id)
+}
+
+// fillBatchResults fills in the results and features of a chunk of features by ID from the response to
+// its request, or with the request's error
+func fillBatchResults(results []BatchResult, features []*geojson.Feature, ids []string, responseResults []BatchResult, responseFeatures []*geojson.Feature, err error) {
+	for i := range results {
+		results[i].ID = ids[i]
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Err = responseResults[i].Err
+		features[i] = responseFeatures[i]
+	}
+}
+
+// affectedFeatures returns the features which were in the responses to a batch
+func affectedFeatures(features []*geojson.Feature) Features {
+	affected := NewFeatures()
+	for _, feature := range features {
+		if feature != nil {
+			affected = append(affected, &Feature{Feature: feature})
+		}
+	}
+	return affected
+}
@@ -0,0 +1,368 @@
+package spatially
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+func storeFeatures(n int) Features {
+	features := NewFeatures()
+	for i := 0; i < n; i++ {
+		feature := NewFeature()
+		feature.Geometry = geojson.NewPointGeometry([]float64{-71.06 + 0.001*float64(i%100), 42.35})
+		feature.Properties = map[string]interface{}{"name": fmt.Sprintf("store %d", i)}
+		features = append(features, feature)
+	}
+	return features
+}
+
+func TestCreateBatch(t *testing.T) {
+	var requests, inFlight, maxInFlight int32
+	var mutex sync.Mutex
+	created := map[string]bool{}
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/spatialdb/features/batch" {
+			t.Error("Unexpected path", req.URL.Path)
+		}
+		atomic.AddInt32(&requests, 1)
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var request createBatchRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if request.LayerID != "layer" || len(request.Features) > 30 {
+			t.Errorf("Unexpected request for layer %s with %d features", request.LayerID, len(request.Features))
+		}
+		var results []batchResponseResult
+		for _, feature := range request.Features {
+			name := feature.PropertyMustString("name")
+			if name == "store 42" {
+				results = append(results, batchResponseResult{Error: "duplicate store"})
+				continue
+			}
+			mutex.Lock()
+			created[name] = true
+			mutex.Unlock()
+			results = append(results, batchResponseResult{ID: "id " + name})
+		}
+		json.NewEncoder(w).Encode(results)
+	})
+	defer server.Close()
+
+	features := storeFeatures(250)
+	report, err := features.CreateBatch(sdb, "layer", &BatchOptions{Size: 30, Concurrency: 3})
+	batchErr, ok := errors.Cause(err).(*BatchError)
+	if !ok || batchErr.Failed != 1 || batchErr.Total != 250 {
+		t.Fatal("Expected 1 feature to fail, got", err)
+	}
+	if requests != 9 || maxInFlight > 3 || maxInFlight < 2 {
+		t.Errorf("Expected 9 requests, 3 at a time, got %d, %d at a time", requests, maxInFlight)
+	}
+	if report.Succeeded != 249 || report.Failed != 1 || len(created) != 249 {
+		t.Errorf("Expected 249 features created, got %d, %d", report.Succeeded, len(created))
+	}
+	for i, result := range report.Results {
+		name := fmt.Sprintf("store %d", i)
+		if i == 42 {
+			if apiErr, ok := asAPIError(result.Err); !ok || apiErr.Message != "duplicate store" {
+				t.Error("Expected the duplicate store to fail, got", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || result.ID != "id "+name || features[i].ID != "id "+name {
+			t.Errorf("Expected %s created, got %+v", name, result)
+		}
+	}
+}
+
+func TestCreateBatchErrors(t *testing.T) {
+	var requests int32
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		var request createBatchRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "database unavailable"}`))
+			return
+		}
+		results := make([]batchResponseResult, len(request.Features))
+		for i, feature := range request.Features {
+			if feature.Geometry.Type != geojson.GeometryPoint {
+				t.Error("Expected the invalid geometry not to be sent")
+			}
+			results[i].ID = "id"
+		}
+		json.NewEncoder(w).Encode(results)
+	}, WithGeometryValidation(RejectInvalidGeometries))
+	defer server.Close()
+
+	features := storeFeatures(4)
+	features[3].Geometry = mustWKT(t, "POLYGON((0 0,1 1,1 0,0 1,0 0))")
+	features = append(features, nil)
+	report, err := features.CreateBatch(sdb, "layer", &BatchOptions{Size: 2, Concurrency: 1})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for i, result := range report.Results {
+		switch i {
+		case 0, 1:
+			if !hasStatusCode(result.Err, http.StatusInternalServerError) {
+				t.Error("Expected the first request to fail, got", result.Err)
+			}
+		case 2:
+			if result.Err != nil {
+				t.Error(result.Err)
+			}
+		case 3:
+			if reasons := reasonsOf(result.Err); len(reasons) != 1 || reasons[0] != SelfIntersection {
+				t.Error("Expected a self intersection, got", result.Err)
+			}
+		case 4:
+			if result.Err == nil {
+				t.Error("Expected an error for a nil feature")
+			}
+		}
+	}
+	if report.Succeeded != 1 || report.Failed != 4 || requests != 2 {
+		t.Errorf("Unexpected report %+v after %d requests", report, requests)
+	}
+
+	report, err = Features{}.CreateBatch(sdb, "layer", nil)
+	if err != nil || len(report.Results) != 0 || requests != 2 {
+		t.Error("Expected nothing to be sent for no features", err)
+	}
+}
+
+func TestCreateBatchRepairedGeometries(t *testing.T) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		var request createBatchRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if len(request.Features) != 1 || request.Features[0].Geometry.Type != geojson.GeometryMultiPolygon {
+			t.Error("Expected the repaired geometry to be sent")
+		}
+		json.NewEncoder(w).Encode([]batchResponseResult{{ID: "id"}})
+	}, WithGeometryValidation(RepairInvalidGeometries))
+	defer server.Close()
+
+	features := storeFeatures(1)
+	geometry := mustWKT(t, "POLYGON((0 0,1 1,1 0,0 1,0 0))")
+	features[0].Geometry = geometry
+	for _, dryRun := range []bool{true, false} {
+		if _, err := features.CreateBatch(sdb, "layer", &BatchOptions{DryRun: dryRun}); err != nil {
+			t.Fatal(err)
+		}
+		if features[0].Geometry != geometry || geometry.Type != geojson.GeometryPolygon {
+			t.Error("Expected the feature to keep its geometry")
+		}
+	}
+	if features[0].ID != "id" {
+		t.Error("Expected the feature to be updated with its ID, got", features[0].ID)
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "PUT" || req.URL.Path != "/spatialdb/features/batch" {
+			t.Error("Unexpected request", req.Method, req.URL.Path)
+		}
+		var request updateBatchRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if !request.DryRun {
+			t.Error("Expected a dry run")
+		}
+		var results []batchResponseResult
+		for _, update := range request.Features {
+			if update.ID == "missing" {
+				results = append(results, batchResponseResult{ID: update.ID, Error: "feature not found"})
+				continue
+			}
+			feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+			feature.ID = update.ID
+			feature.Properties = update.Properties
+			results = append(results, batchResponseResult{ID: update.ID, Feature: feature})
+		}
+		json.NewEncoder(w).Encode(results)
+	})
+	defer server.Close()
+
+	features := NewFeatures()
+	report, err := features.UpdateBatch(sdb, map[string]map[string]interface{}{
+		"c":       {"name": "Starbucks Cambridge"},
+		"a":       {"name": "Starbucks Boston"},
+		"missing": {"name": "Starbucks Somerville"},
+		"b":       {"name": "Starbucks Brookline"},
+	}, &BatchOptions{Size: 2, DryRun: true})
+	if batchErr, ok := errors.Cause(err).(*BatchError); !ok || batchErr.Failed != 1 {
+		t.Fatal("Expected the missing feature to fail, got", err)
+	}
+	var ids []string
+	for _, result := range report.Results {
+		ids = append(ids, result.ID)
+	}
+	if fmt.Sprint(ids) != "[a b c missing]" || report.Results[3].Err == nil {
+		t.Errorf("Unexpected results %+v", report.Results)
+	}
+	if len(features) != 3 || features[2].ID != "c" || features[2].PropertyMustString("name") != "Starbucks Cambridge" {
+		t.Errorf("Expected the 3 updated features, got %+v", features)
+	}
+}
+
+func TestDeleteBatch(t *testing.T) {
+	var deleted []string
+	var mutex sync.Mutex
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/spatialdb/features":
+			var request getFeaturesRequest
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			if request.LayerID != "layer" || request.SpatialConstraint == nil {
+				t.Errorf("Unexpected request %+v", request)
+			}
+			var features []*geojson.Feature
+			for _, id := range []interface{}{"a", "b", 12345678} {
+				feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+				feature.ID = id
+				features = append(features, feature)
+			}
+			json.NewEncoder(w).Encode(features)
+		case req.Method == "DELETE" && req.URL.Path == "/spatialdb/features/batch":
+			var request deleteBatchRequest
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			var results []batchResponseResult
+			for _, id := range request.IDs {
+				feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+				feature.ID = id
+				results = append(results, batchResponseResult{ID: id, Feature: feature})
+				if !request.DryRun {
+					mutex.Lock()
+					deleted = append(deleted, id)
+					mutex.Unlock()
+				}
+			}
+			json.NewEncoder(w).Encode(results)
+		default:
+			t.Error("Unexpected request", req.Method, req.URL.Path)
+		}
+	})
+	defer server.Close()
+
+	features := NewFeatures()
+	constraint := &SpatialConstraint{WKT: "POINT(-71.06 42.35)", Radius: 100}
+	report, err := features.DeleteBySpatialConstraint(sdb, "layer", constraint, &BatchOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 3 || len(features) != 3 || len(deleted) != 0 {
+		t.Errorf("Expected 3 features to be listed and none deleted, got %+v, %d deleted", report, len(deleted))
+	}
+	// numeric IDs are decoded as float64 and sent whole
+	if id := report.Results[2].ID; id != "12345678" {
+		t.Error("Expected the numeric ID 12345678, got", id)
+	}
+
+	report, err = features.DeleteBatch(sdb, []string{"a", "b", "c", "d", "e"}, &BatchOptions{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 5 || len(features) != 5 || len(deleted) != 5 {
+		t.Errorf("Expected 5 features to be deleted, got %+v, %d deleted", report, len(deleted))
+	}
+}
+
+func TestDeleteBySpatialConstraintPages(t *testing.T) {
+	var deleted []string
+	var mutex sync.Mutex
+	pages := 0
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/spatialdb/features":
+			var request getFeaturesRequest
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			if request.SpatialConstraint == nil {
+				t.Errorf("Expected the spatial constraint in every request, got %+v", request)
+			}
+			pages++
+			start := 0
+			fmt.Sscanf(request.PageToken, "after %d", &start)
+			var features []*geojson.Feature
+			for i := start; i < 7 && i < start+3; i++ {
+				features = append(features, pageFeature(i))
+			}
+			next := ""
+			if start+3 < 7 {
+				next = fmt.Sprintf("after %d", start+3)
+			}
+			j, _ := json.Marshal(features)
+			fmt.Fprintf(w, `{"features": %s, "nextPageToken": %q}`, j, next)
+		case req.Method == "DELETE" && req.URL.Path == "/spatialdb/features/batch":
+			var request deleteBatchRequest
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			var results []batchResponseResult
+			for _, id := range request.IDs {
+				feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+				feature.ID = id
+				results = append(results, batchResponseResult{ID: id, Feature: feature})
+				if !request.DryRun {
+					mutex.Lock()
+					deleted = append(deleted, id)
+					mutex.Unlock()
+				}
+			}
+			json.NewEncoder(w).Encode(results)
+		default:
+			t.Error("Unexpected request", req.Method, req.URL.Path)
+		}
+	})
+	defer server.Close()
+
+	features := NewFeatures()
+	constraint := &SpatialConstraint{WKT: "POINT(-71.06 42.35)", Radius: 100}
+	report, err := features.DeleteBySpatialConstraint(sdb, "layer", constraint, &BatchOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 7 || len(features) != 7 || len(deleted) != 0 || pages != 3 {
+		t.Errorf("Expected the 7 features of 3 pages to be listed and none deleted, got %+v, %d deleted from %d pages", report, len(deleted), pages)
+	}
+
+	report, err = features.DeleteBySpatialConstraint(sdb, "layer", constraint, &BatchOptions{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(deleted)
+	if report.Succeeded != 7 || fmt.Sprint(deleted) != "[store 0 store 1 store 2 store 3 store 4 store 5 store 6]" {
+		t.Errorf("Expected every feature to be deleted, got %+v, deleted %v", report, deleted)
+	}
+}
@@ -0,0 +1,165 @@
+package spatially
+
+import (
+	"fmt"
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// DefaultBufferSegments is the number of segments approximating a circle when Buffer is given none
+const DefaultBufferSegments = 32
+
+// Buffer returns a polygon covering the points within a distance in meters of g, such as the area a
+// SpatialConstraint with a Radius queries. Circles are approximated with the given number of segments,
+// DefaultBufferSegments when it's 0. The circle around a single point is geodesic. Other geometries are
+// buffered on a plane tangent to the WGS84 ellipsoid at their center, which is accurate for geometries
+// up to a few hundred kilometers across. The result is a Polygon, or a MultiPolygon when the buffered
+// parts don't overlap.
+func Buffer(g *geojson.Geometry, meters float64, segments int) (*geojson.Geometry, error) {
+	if meters <= 0 || math.IsNaN(meters) || math.IsInf(meters, 0) {
+		return nil, fmt.Errorf("buffer distance must be positive, got %v", meters)
+	}
+	if segments <= 0 {
+		segments = DefaultBufferSegments
+	}
+	// circles are made of two half circles
+	segments = int(math.Max(4, float64(segments+segments%2)))
+	points, lines, polygons := flatten(g)
+	if len(points)+len(lines)+len(polygons) == 0 {
+		return nil, fmt.Errorf("can't buffer an empty geometry")
+	}
+	if len(points) == 1 && len(lines) == 0 && len(polygons) == 0 {
+		return geojson.NewPolygonGeometry([][][]float64{geodesicCircle(points[0], meters, segments)}), nil
+	}
+
+	projection := newLocalProjection(BBox(g))
+	var pieces []planarPolygon
+	for _, p := range points {
+		pieces = append(pieces, projection.forwardPolygon([][][]float64{geodesicCircle(p, meters, segments)}))
+	}
+	buffer := func(line []vec) {
+		length := 0.0
+		for i := 0; i+1 < len(line); i++ {
+			if line[i] != line[i+1] {
+				pieces = append(pieces, capsule(line[i], line[i+1], meters, segments))
+				length += math.Hypot(line[i+1][0]-line[i][0], line[i+1][1]-line[i][1])
+			}
+		}
+		if length == 0 && len(line) > 0 {
+			pieces = append(pieces, projection.forwardPolygon([][][]float64{geodesicCircle(projection.inverse(line[0]), meters, segments)}))
+		}
+	}
+	for _, line := range lines {
+		buffer(projection.forwardLine(line))
+	}
+	for _, polygon := range polygons {
+		if len(polygon) == 0 || len(polygon[0]) < 4 {
+			continue
+		}
+		planar := projection.forwardPolygon(polygon)
+		pieces = append(pieces, planar)
+		for _, r := range planar {
+			buffer(r)
+		}
+	}
+	return projection.inverseGeometry(unionPolygons(pieces)), nil
+}
+
+// geodesicCircle returns the ring of points at a distance in meters from a center, counterclockwise
+func geodesicCircle(center []float64, meters float64, segments int) [][]float64 {
+	circle := make([][]float64, segments+1)
+	for i := 0; i < segments; i++ {
+		circle[i] = geodesicDestination(center, 360-360*float64(i)/float64(segments), meters)
+	}
+	circle[segments] = circle[0]
+	return circle
+}
+
+// capsule returns the planar polygon covering the points within a distance of the edge p q: a rectangle
+// along the edge capped by half circles around its ends
+func capsule(p, q vec, radius float64, segments int) planarPolygon {
+	d := q.sub(p)
+	angle := math.Atan2(d[1], d[0])
+	var r ring
+	for _, end := range []struct {
+		center vec
+		start  float64
+	}{{q, angle - math.Pi/2}, {p, angle + math.Pi/2}} {
+		for i := 0; i <= segments/2; i++ {
+			sin, cos := math.Sincos(end.start + 2*math.Pi*float64(i)/float64(segments))
+			r = append(r, vec{end.center[0] + radius*cos, end.center[1] + radius*sin})
+		}
+	}
+	return planarPolygon{append(r, r[0])}
+}
+
+// localProjection maps longitude & latitude to meters east and north of an origin, on a plane tangent
+// to the WGS84 ellipsoid there. Distances are accurate near the origin.
+type localProjection struct {
+	origin vec
+	// scale is the number of meters in a degree of longitude and latitude at the origin
+	scale vec
+}
+
+// newLocalProjection creates a projection centered on the [west, south, east, north] bounds
+func newLocalProjection(bbox []float64) localProjection {
+	lon, lat := (bbox[0]+bbox[2])/2, (bbox[1]+bbox[3])/2
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	w := 1 - e2*sinLat*sinLat
+	// radii of curvature along the parallel and the meridian
+	n := wgs84SemiMajorAxis / math.Sqrt(w)
+	m := wgs84SemiMajorAxis * (1 - e2) / (w * math.Sqrt(w))
+	return localProjection{
+		origin: vec{lon, lat},
+		scale:  vec{n * cosLat * math.Pi / 180, m * math.Pi / 180},
+	}
+}
+
+func (p localProjection) forward(c []float64) vec {
+	return vec{(c[0] - p.origin[0]) * p.scale[0], (c[1] - p.origin[1]) * p.scale[1]}
+}
+
+func (p localProjection) inverse(v vec) []float64 {
+	return []float64{p.origin[0] + v[0]/p.scale[0], p.origin[1] + v[1]/p.scale[1]}
+}
+
+func (p localProjection) forwardLine(line [][]float64) []vec {
+	projected := make([]vec, len(line))
+	for i, c := range line {
+		projected[i] = p.forward(c)
+	}
+	return projected
+}
+
+func (p localProjection) forwardPolygon(polygon [][][]float64) planarPolygon {
+	projected := make(planarPolygon, len(polygon))
+	for i, r := range polygon {
+		projected[i] = p.forwardLine(r)
+	}
+	return projected
+}
+
+func (p localProjection) inversePolygon(polygon planarPolygon) [][][]float64 {
+	unprojected := make([][][]float64, len(polygon))
+	for i, r := range polygon {
+		unprojected[i] = make([][]float64, len(r))
+		for j, v := range r {
+			unprojected[i][j] = p.inverse(v)
+		}
+	}
+	return unprojected
+}
+
+// inverseGeometry returns the polygons as a Polygon when there's one and a MultiPolygon otherwise
+func (p localProjection) inverseGeometry(polygons []planarPolygon) *geojson.Geometry {
+	if len(polygons) == 1 {
+		return geojson.NewPolygonGeometry(p.inversePolygon(polygons[0]))
+	}
+	multi := make([][][][]float64, len(polygons))
+	for i, polygon := range polygons {
+		multi[i] = p.inversePolygon(polygon)
+	}
+	return geojson.NewMultiPolygonGeometry(multi...)
+}
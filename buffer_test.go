@@ -0,0 +1,136 @@
+package spatially
+
+import (
+	"math"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestBufferPoint(t *testing.T) {
+	center := []float64{-71.06, 42.35}
+	g, err := Buffer(geojson.NewPointGeometry(center), 1000, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryPolygon || len(g.Polygon) != 1 || len(g.Polygon[0]) != 65 {
+		t.Fatalf("Expected a polygon of 65 points, got %+v", g)
+	}
+	for _, p := range g.Polygon[0] {
+		if d := geodesicDistance(center, p); math.Abs(d-1000) > 1e-3 {
+			t.Fatal("Expected points 1000m from the center, got", d)
+		}
+	}
+	// the area of a regular polygon inscribed in the circle
+	expected := 64 / 2.0 * 1000 * 1000 * math.Sin(2*math.Pi/64)
+	if area := Area(g); math.Abs(area-expected)/expected > 1e-3 {
+		t.Errorf("Expected an area of %f m², got %f", expected, area)
+	}
+	if ringArea(g.Polygon[0]) <= 0 {
+		t.Error("Expected a counterclockwise ring")
+	}
+}
+
+func TestBufferLineString(t *testing.T) {
+	line := mustWKT(t, "LINESTRING(-71.07 42.35,-71.05 42.35,-71.05 42.36)")
+	g, err := Buffer(line, 100, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryPolygon || len(g.Polygon) != 1 {
+		t.Fatalf("Expected a polygon without holes, got %+v", g)
+	}
+	// a rectangle along the line with a circle made of its ends, and the corner of the turn, whose
+	// inside overlaps
+	r := 100.0
+	expected := 2*r*Length(line) + math.Pi*r*r + math.Pi*r*r/4 - r*r
+	if area := Area(g); math.Abs(area-expected)/expected > 0.01 {
+		t.Errorf("Expected an area of %f m², got %f", expected, area)
+	}
+	if !Contains(g, line) {
+		t.Error("Expected the buffer to contain the line")
+	}
+	near := geojson.NewPointGeometry(geodesicDestination([]float64{-71.06, 42.35}, 180, 90))
+	far := geojson.NewPointGeometry(geodesicDestination([]float64{-71.06, 42.35}, 180, 110))
+	if !Contains(g, near) || Contains(g, far) {
+		t.Error("Expected the buffer to contain points 90m from the line and not 110m")
+	}
+}
+
+func TestBufferPolygon(t *testing.T) {
+	polygon := mustWKT(t, "POLYGON((-71.07 42.35,-71.05 42.35,-71.05 42.36,-71.07 42.36,-71.07 42.35),(-71.065 42.354,-71.064 42.354,-71.064 42.355,-71.065 42.355,-71.065 42.354))")
+	g, err := Buffer(polygon, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hole is about 80m across, so the buffer fills it
+	if g.Type != geojson.GeometryPolygon || len(g.Polygon) != 1 {
+		t.Fatalf("Expected a polygon without holes, got %d rings", len(g.Polygon))
+	}
+	r := 200.0
+	shell := geojson.NewPolygonGeometry(polygon.Polygon[:1])
+	expected := Area(shell) + Perimeter(shell)*r + math.Pi*r*r
+	if area := Area(g); math.Abs(area-expected)/expected > 0.01 {
+		t.Errorf("Expected an area of %f m², got %f", expected, area)
+	}
+	if !Contains(g, polygon) {
+		t.Error("Expected the buffer to contain the polygon")
+	}
+
+	g, err = Buffer(polygon, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryPolygon || len(g.Polygon) != 2 {
+		t.Fatalf("Expected a polygon with a hole, got %+v", g)
+	}
+
+	// the arcs around the corners of a square, from the capsules of both edges, meet at rounding distance
+	square := mustWKT(t, "POLYGON((0 0,0.009 0,0.009 0.009,0 0.009,0 0))")
+	for _, r := range []float64{100, 1000} {
+		g, err = Buffer(square, r, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := Area(square) + Perimeter(square)*r + math.Pi*r*r
+		if area := Area(g); math.Abs(area-expected)/expected > 0.01 {
+			t.Errorf("Expected an area of %f m² for a %fm buffer, got %f", expected, r, area)
+		}
+	}
+}
+
+func TestBufferMultiPoint(t *testing.T) {
+	g, err := Buffer(mustWKT(t, "MULTIPOINT(-71.06 42.35,-71.059 42.35)"), 100, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryPolygon {
+		t.Error("Expected overlapping circles to make a polygon, got", g.Type)
+	}
+	g, err = Buffer(mustWKT(t, "MULTIPOINT(-71.06 42.35,-71.05 42.35)"), 100, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryMultiPolygon || len(g.MultiPolygon) != 2 {
+		t.Error("Expected distant circles to make a multipolygon, got", g.Type)
+	}
+}
+
+func TestBufferInvalid(t *testing.T) {
+	if _, err := Buffer(mustWKT(t, "POINT(1 2)"), 0, 8); err == nil {
+		t.Error("Expected an error for a distance of 0")
+	}
+	if _, err := Buffer(mustWKT(t, "POINT EMPTY"), 10, 8); err == nil {
+		t.Error("Expected an error for an empty geometry")
+	}
+}
+
+func BenchmarkBufferPolygon(b *testing.B) {
+	polygon := geojson.NewPolygonGeometry(benchmarkPolygon(-71.06, 42.35, 2000))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Buffer(polygon, 100, 16); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
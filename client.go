@@ -0,0 +1,132 @@
+package spatially
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is the User-Agent header sent by clients that don't configure their own
+const DefaultUserAgent = "go-spatially"
+
+// Client holds the transport settings used to reach the Spatially API. Every layer, feature,
+// ATA and grid operation is sent through the Client of the API it is given.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	timeout    time.Duration
+	retry      RetryPolicy
+	throttles  map[EndpointFamily]*throttle
+	basePath   string
+	validation GeometryValidation
+}
+
+// ClientOption configures a Client
+type ClientOption func(c *Client)
+
+// WithBaseURL sets the URL requests are sent to, e.g. a staging environment or a local test server.
+// It defaults to SpatiallyAPI.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests, e.g. one configured with a proxy
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the time limit for each request. The http.Client given with WithHTTPClient
+// is copied rather than modified.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient creates a new Client. Without options it sends requests to SpatiallyAPI
+// using a plain http.Client.
+func NewClient(options ...ClientOption) *Client {
+	c := &Client{
+		baseURL:   SpatiallyAPI,
+		userAgent: DefaultUserAgent,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+	if base, err := url.Parse(c.baseURL); err == nil {
+		c.basePath = base.Path
+	}
+	if c.timeout > 0 {
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
+}
+
+// BaseURL returns the URL requests are sent to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// NewRequest creates a request for the given API path, e.g. "/spatialdb/layers"
+func (c *Client) NewRequest(method, path string, body io.Reader) (*http.Request, error) {
+	return c.NewRequestContext(context.Background(), method, path, body)
+}
+
+// NewRequestContext creates a request for the given API path that is canceled with ctx
+func (c *Client) NewRequestContext(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		request.Header.Set("User-Agent", c.userAgent)
+	}
+	return request, nil
+}
+
+// Do sends the request with the client's http.Client, waiting for the rate and in flight limits of
+// its endpoint family and retrying it as configured by the client's RetryPolicy
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	attempts := 1
+	if c.retry.MaxAttempts > 1 && isIdempotent(request) {
+		attempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.sendThrottled(request)
+		if attempt == attempts || !shouldRetry(request, resp, err) {
+			return resp, err
+		}
+		next, canRewind := rewind(request)
+		if !canRewind {
+			return resp, err
+		}
+		wait := c.retry.backoff(attempt, resp)
+		if resp != nil {
+			discard(resp)
+		}
+		if err := sleep(request.Context(), wait); err != nil {
+			return nil, err
+		}
+		request = next
+	}
+}
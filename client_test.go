@@ -0,0 +1,126 @@
+package spatially
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpmock "gopkg.in/jarcoal/httpmock.v1"
+)
+
+// newTestAPI starts a server that answers the gateway exchange and hands every other request to
+// handler, and returns an API pointed at it
+func newTestAPI(t *testing.T, handler http.HandlerFunc, options ...ClientOption) (API, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/gateway/client" {
+			json.NewEncoder(w).Encode(gatewayResponse{"authToken"})
+			return
+		}
+		handler(w, req)
+	}))
+	sdb, err := NewAPI(applicationCode, applicationKey, append([]ClientOption{WithBaseURL(server.URL)}, options...)...)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return sdb, server
+}
+
+func TestClientDefaults(t *testing.T) {
+	client := NewClient()
+	if client.BaseURL() != SpatiallyAPI {
+		t.Error("Default base URL should be", SpatiallyAPI, "got", client.BaseURL())
+	}
+	request, err := client.NewRequest("GET", "/spatialdb/layers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.URL.String() != SpatiallyAPI+"/spatialdb/layers" {
+		t.Error("Invalid request url", request.URL.String())
+	}
+	if request.Header.Get("User-Agent") != DefaultUserAgent {
+		t.Error("Invalid default user agent", request.Header.Get("User-Agent"))
+	}
+}
+
+func TestClientBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("User-Agent") != "stores-importer/1.0" {
+			t.Error("Invalid user agent", req.Header.Get("User-Agent"))
+		}
+		switch req.URL.Path {
+		case "/gateway/client":
+			json.NewEncoder(w).Encode(gatewayResponse{"authToken"})
+		case "/spatialdb/layers":
+			if req.Header.Get("Authorization") != "Bearer authToken" {
+				t.Error("Invalid authorization header", req.Header.Get("Authorization"))
+			}
+			json.NewEncoder(w).Encode(Layers{&Layer{ID: "1", Name: "layer1"}})
+		default:
+			t.Error("Unexpected request path", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sdb, err := NewAPI(applicationCode, applicationKey, WithBaseURL(server.URL+"/"), WithUserAgent("stores-importer/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdb.(ClientProvider).Client().BaseURL() != server.URL {
+		t.Error("Base URL trailing slash should be trimmed, got", sdb.(ClientProvider).Client().BaseURL())
+	}
+	layers := NewLayers()
+	if err := layers.Get(sdb); err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].Name != "layer1" {
+		t.Error("Invalid layers", layers)
+	}
+}
+
+func TestClientHTTPClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		json.NewEncoder(w).Encode(gatewayResponse{"authToken"})
+	}))
+	defer server.Close()
+	httpClient := &http.Client{}
+	_, err := NewAPI(applicationCode, applicationKey, WithBaseURL(server.URL), WithHTTPClient(httpClient), WithTimeout(10*time.Millisecond))
+	if err == nil {
+		t.Error("Expected the gateway request to time out")
+	}
+	if httpClient.Timeout != 0 {
+		t.Error("The given http client should not be modified")
+	}
+}
+
+// tokenAPI is an API implemented outside the package, without a Client
+type tokenAPI struct{}
+
+func (tokenAPI) PrepareRequest(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer outsideToken")
+}
+
+func (tokenAPI) Error(response []byte) error {
+	return &APIError{Body: response}
+}
+
+func TestClientOfOutsideAPI(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", SpatiallyAPI+"/spatialdb/layers", func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "Bearer outsideToken" {
+			t.Error("Invalid authorization header", req.Header.Get("Authorization"))
+		}
+		return httpmock.NewJsonResponse(200, Layers{&Layer{ID: "1", Name: "layer1"}})
+	})
+	layers := NewLayers()
+	if err := layers.Get(tokenAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 {
+		t.Error("Invalid layers", layers)
+	}
+}
@@ -0,0 +1,113 @@
+package spatially
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+var spatialConstraintTypes = []string{
+	"intersect",
+	"within",
+	"contains",
+	"disjoint",
+	"distance",
+}
+
+func (t SpatialConstraintType) String() string {
+	if t < 0 || int(t) >= len(spatialConstraintTypes) {
+		return fmt.Sprintf("SpatialConstraintType(%d)", int(t))
+	}
+	return spatialConstraintTypes[t]
+}
+
+// MarshalJSON writes the type's name
+func (t SpatialConstraintType) MarshalJSON() ([]byte, error) {
+	if t < 0 || int(t) >= len(spatialConstraintTypes) {
+		return nil, fmt.Errorf("unknown spatial constraint type %d", int(t))
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON reads the type's name, or the number it used to be sent as
+func (t *SpatialConstraintType) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		if number < 0 || number >= len(spatialConstraintTypes) {
+			return fmt.Errorf("unknown spatial constraint type %d", number)
+		}
+		*t = SpatialConstraintType(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return errors.Wrap(err, "spatial constraint type")
+	}
+	for i, n := range spatialConstraintTypes {
+		if n == name {
+			*t = SpatialConstraintType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown spatial constraint type %q", name)
+}
+
+// validate checks the constraint before it's sent
+func (s *SpatialConstraint) validate() error {
+	if s.Type < 0 || int(s.Type) >= len(spatialConstraintTypes) {
+		return fmt.Errorf("unknown spatial constraint type %d", int(s.Type))
+	}
+	for _, meters := range []float64{s.Radius, s.MinDistance, s.MaxDistance} {
+		if meters < 0 || math.IsNaN(meters) || math.IsInf(meters, 0) {
+			return fmt.Errorf("spatial constraint distances must not be negative, got %v", meters)
+		}
+	}
+	if s.Type != SpatialConstraintDistance {
+		if s.MinDistance != 0 || s.MaxDistance != 0 {
+			return fmt.Errorf("min and max distance only apply to a %s spatial constraint, not %s", SpatialConstraintDistance, s.Type)
+		}
+		return nil
+	}
+	if s.MinDistance == 0 && s.MaxDistance == 0 {
+		return fmt.Errorf("distance spatial constraint needs a min or max distance")
+	}
+	if s.MaxDistance != 0 && s.MinDistance > s.MaxDistance {
+		return fmt.Errorf("distance spatial constraint min distance %v is more than its max distance %v", s.MinDistance, s.MaxDistance)
+	}
+	return nil
+}
+
+// Matches reports whether the constraint selects a feature with the geometry g, evaluating it locally
+// with the predicates of this package, e.g. to check features received earlier. Distances are measured
+// along geodesics between the closest points of g and the shape.
+func (s *SpatialConstraint) Matches(g *geojson.Geometry) (bool, error) {
+	if err := s.validate(); err != nil {
+		return false, err
+	}
+	shape, err := WKTToGeometry(s.WKT)
+	if err != nil {
+		return false, errors.Wrap(err, "spatial constraint wkt")
+	}
+	if s.Radius > 0 {
+		if shape, err = Buffer(shape, s.Radius, 0); err != nil {
+			return false, errors.Wrap(err, "spatial constraint radius")
+		}
+	}
+	switch s.Type {
+	case SpatialConstraintWithin:
+		return Within(g, shape), nil
+	case SpatialConstraintContains:
+		return Contains(g, shape), nil
+	case SpatialConstraintDisjoint:
+		return Disjoint(g, shape), nil
+	case SpatialConstraintDistance:
+		if s.MinDistance > 0 && DWithin(g, shape, s.MinDistance) {
+			return false, nil
+		}
+		return s.MaxDistance == 0 || DWithin(g, shape, s.MaxDistance), nil
+	}
+	return Intersects(g, shape), nil
+}
@@ -0,0 +1,118 @@
+package spatially
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSpatialConstraintJSON(t *testing.T) {
+	for _, test := range []struct {
+		constraint SpatialConstraint
+		expected   string
+	}{
+		{SpatialConstraint{WKT: "POINT(0 0)", Radius: 100}, `{"wkt":"POINT(0 0)","radius":100,"type":"intersect"}`},
+		{SpatialConstraint{WKT: "POINT(0 0)", Type: SpatialConstraintWithin}, `{"wkt":"POINT(0 0)","radius":0,"type":"within"}`},
+		{SpatialConstraint{WKT: "POINT(0 0)", Type: SpatialConstraintContains}, `{"wkt":"POINT(0 0)","radius":0,"type":"contains"}`},
+		{SpatialConstraint{WKT: "POINT(0 0)", Type: SpatialConstraintDisjoint}, `{"wkt":"POINT(0 0)","radius":0,"type":"disjoint"}`},
+		{
+			SpatialConstraint{WKT: "POINT(0 0)", Type: SpatialConstraintDistance, MinDistance: 100, MaxDistance: 500},
+			`{"wkt":"POINT(0 0)","radius":0,"type":"distance","minDistance":100,"maxDistance":500}`,
+		},
+	} {
+		j, err := json.Marshal(test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(j) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, j)
+		}
+		var constraint SpatialConstraint
+		if err := json.Unmarshal(j, &constraint); err != nil {
+			t.Fatal(err)
+		}
+		if constraint != test.constraint {
+			t.Errorf("Expected %+v, got %+v", test.constraint, constraint)
+		}
+	}
+
+	var constraint SpatialConstraint
+	if err := json.Unmarshal([]byte(`{"wkt":"POINT(0 0)","type":1}`), &constraint); err != nil || constraint.Type != SpatialConstraintWithin {
+		t.Errorf("Expected a numbered type to be read, got %v %v", constraint.Type, err)
+	}
+	for _, j := range []string{`{"type":"overlaps"}`, `{"type":9}`, `{"type":true}`} {
+		if err := json.Unmarshal([]byte(j), &constraint); err == nil {
+			t.Error("Expected an error for", j)
+		}
+	}
+	if _, err := json.Marshal(SpatialConstraint{Type: SpatialConstraintType(9)}); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+func TestSpatialConstraintMatches(t *testing.T) {
+	// a 1km square and points 0, 500m, 1.5km and 5km from its west edge, going west
+	square := "POLYGON((0 0,0.009 0,0.009 0.009,0 0.009,0 0))"
+	points := []string{"POINT(0.0045 0.0045)", "POINT(-0.0045 0.0045)", "POINT(-0.0135 0.0045)", "POINT(-0.045 0.0045)"}
+	for _, test := range []struct {
+		constraint SpatialConstraint
+		matches    []bool
+	}{
+		{SpatialConstraint{WKT: square}, []bool{true, false, false, false}},
+		{SpatialConstraint{WKT: square, Radius: 1000}, []bool{true, true, false, false}},
+		{SpatialConstraint{WKT: square, Type: SpatialConstraintWithin}, []bool{true, false, false, false}},
+		{SpatialConstraint{WKT: square, Type: SpatialConstraintDisjoint}, []bool{false, true, true, true}},
+		{SpatialConstraint{WKT: square, Type: SpatialConstraintDistance, MaxDistance: 1000}, []bool{true, true, false, false}},
+		{SpatialConstraint{WKT: square, Type: SpatialConstraintDistance, MinDistance: 1000, MaxDistance: 2000}, []bool{false, false, true, false}},
+		{SpatialConstraint{WKT: square, Type: SpatialConstraintDistance, MinDistance: 1000}, []bool{false, false, true, true}},
+	} {
+		for i, point := range points {
+			matches, err := test.constraint.Matches(mustWKT(t, point))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if matches != test.matches[i] {
+				t.Errorf("Expected %s to match %+v: %t", point, test.constraint, test.matches[i])
+			}
+		}
+	}
+	// the square contains its center, not the other way around
+	contains := SpatialConstraint{WKT: "POINT(0.0045 0.0045)", Type: SpatialConstraintContains}
+	for wkt, expected := range map[string]bool{square: true, "POINT(0.0045 0.0045)": true, "POINT(0 0)": false} {
+		if matches, err := contains.Matches(mustWKT(t, wkt)); err != nil || matches != expected {
+			t.Errorf("Expected %s to contain the point: %t, got %t %v", wkt, expected, matches, err)
+		}
+	}
+}
+
+func TestSpatialConstraintValidation(t *testing.T) {
+	for _, constraint := range []*SpatialConstraint{
+		{WKT: "POINT(0 0)", Type: SpatialConstraintType(7)},
+		{WKT: "POINT(0 0)", Radius: -1},
+		{WKT: "POINT(0 0)", MaxDistance: 100},
+		{WKT: "POINT(0 0)", Type: SpatialConstraintDistance},
+		{WKT: "POINT(0 0)", Type: SpatialConstraintDistance, MinDistance: 200, MaxDistance: 100},
+	} {
+		if _, err := constraint.Matches(mustWKT(t, "POINT(0 0)")); err == nil {
+			t.Errorf("Expected an error for %+v", constraint)
+		}
+	}
+	// a distance of 0 is no distance
+	for _, constraint := range []*SpatialConstraint{
+		{WKT: "POINT(0 0)", Radius: 0},
+		{WKT: "POINT(0 0)", Type: SpatialConstraintDistance, MinDistance: 0, MaxDistance: 100},
+	} {
+		if err := constraint.validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", constraint, err)
+		}
+	}
+
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		t.Error("Expected the invalid constraint not to be sent")
+	})
+	defer server.Close()
+	features := NewFeatures()
+	if err := features.GetBySpatialConstraint(sdb, "layer", &SpatialConstraint{WKT: "POINT(0 0)", Type: SpatialConstraintDistance}); err == nil {
+		t.Error("Expected an error for a distance constraint without distances")
+	}
+}
@@ -0,0 +1,109 @@
+package spatially
+
+import (
+	"fmt"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// mapCoordinates returns a copy of g with every coordinate replaced by the result of fn
+func mapCoordinates(g *geojson.Geometry, fn func(p []float64) ([]float64, error)) (*geojson.Geometry, error) {
+	if g == nil {
+		return nil, fmt.Errorf("nil geometry")
+	}
+	var err error
+	mapLine := func(line [][]float64) [][]float64 {
+		if line == nil {
+			return nil
+		}
+		mapped := make([][]float64, len(line))
+		for i, p := range line {
+			if err != nil {
+				return nil
+			}
+			mapped[i], err = fn(p)
+		}
+		return mapped
+	}
+	mapLines := func(lines [][][]float64) [][][]float64 {
+		if lines == nil {
+			return nil
+		}
+		mapped := make([][][]float64, len(lines))
+		for i, line := range lines {
+			mapped[i] = mapLine(line)
+		}
+		return mapped
+	}
+	c := &geojson.Geometry{Type: g.Type, CRS: g.CRS}
+	switch g.Type {
+	case geojson.GeometryPoint:
+		if len(g.Point) > 0 {
+			c.Point, err = fn(g.Point)
+		}
+	case geojson.GeometryMultiPoint:
+		c.MultiPoint = mapLine(g.MultiPoint)
+	case geojson.GeometryLineString:
+		c.LineString = mapLine(g.LineString)
+	case geojson.GeometryMultiLineString:
+		c.MultiLineString = mapLines(g.MultiLineString)
+	case geojson.GeometryPolygon:
+		c.Polygon = mapLines(g.Polygon)
+	case geojson.GeometryMultiPolygon:
+		if g.MultiPolygon != nil {
+			c.MultiPolygon = make([][][][]float64, len(g.MultiPolygon))
+			for i, polygon := range g.MultiPolygon {
+				c.MultiPolygon[i] = mapLines(polygon)
+			}
+		}
+	case geojson.GeometryCollection:
+		c.Geometries = make([]*geojson.Geometry, len(g.Geometries))
+		for i, geometry := range g.Geometries {
+			if c.Geometries[i], err = mapCoordinates(geometry, fn); err != nil {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown or unimplemented geometry '%s'", g.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// flatten collects the points, linestrings and polygons of g, looking into multi geometries and collections
+func flatten(g *geojson.Geometry) (points [][]float64, lines [][][]float64, polygons [][][][]float64) {
+	var walk func(g *geojson.Geometry)
+	walk = func(g *geojson.Geometry) {
+		if g == nil {
+			return
+		}
+		switch g.Type {
+		case geojson.GeometryPoint:
+			if len(g.Point) > 0 {
+				points = append(points, g.Point)
+			}
+		case geojson.GeometryMultiPoint:
+			points = append(points, g.MultiPoint...)
+		case geojson.GeometryLineString:
+			if len(g.LineString) > 0 {
+				lines = append(lines, g.LineString)
+			}
+		case geojson.GeometryMultiLineString:
+			lines = append(lines, g.MultiLineString...)
+		case geojson.GeometryPolygon:
+			if len(g.Polygon) > 0 {
+				polygons = append(polygons, g.Polygon)
+			}
+		case geojson.GeometryMultiPolygon:
+			polygons = append(polygons, g.MultiPolygon...)
+		case geojson.GeometryCollection:
+			for _, member := range g.Geometries {
+				walk(member)
+			}
+		}
+	}
+	walk(g)
+	return points, lines, polygons
+}
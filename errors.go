@@ -0,0 +1,108 @@
+package spatially
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned when Spatially responds with an unexpected status code. Use errors.As,
+// or one of IsNotFound, IsUnauthorized and IsRateLimited, to branch on it.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	Message    string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	if e.Method == "" {
+		return fmt.Sprintf("spatially: %v", message)
+	}
+	return fmt.Sprintf("spatially %v %v: %d %v", e.Method, e.Endpoint, e.StatusCode, message)
+}
+
+// newAPIError creates an APIError for the response to the request. The message is read from the
+// error body when the server sent one.
+func newAPIError(request *http.Request, resp *http.Response, responseBody []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     request.Method,
+		Endpoint:   request.URL.Path,
+		Message:    errorMessage(responseBody),
+		Body:       responseBody,
+	}
+}
+
+// RefreshError is returned when Spatially rejected a request's token and generating a new one failed. It
+// carries the rejected request's APIError, so IsUnauthorized reports it, and the error of the refresh.
+// It's what pkg/errors' Cause returns for the errors wrapping it.
+type RefreshError struct {
+	*APIError
+	Err error
+}
+
+func (e *RefreshError) Error() string {
+	return fmt.Sprintf("%v: refresh token: %v", e.APIError, e.Err)
+}
+
+// Unwrap returns the APIError of the rejected request
+func (e *RefreshError) Unwrap() error {
+	return e.APIError
+}
+
+type requestError struct {
+	Message string `json:"message"`
+}
+
+func errorMessage(responseBody []byte) string {
+	var body requestError
+	if err := json.Unmarshal(responseBody, &body); err != nil {
+		return strings.TrimSpace(string(responseBody))
+	}
+	return body.Message
+}
+
+// IsNotFound reports whether err is an APIError for a resource that does not exist
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError for a request with a missing or invalid token
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+// IsRateLimited reports whether err is an APIError for a request that was throttled
+func IsRateLimited(err error) bool {
+	return hasStatusCode(err, http.StatusTooManyRequests)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == statusCode
+}
+
+// asAPIError finds an APIError in err's chain, following both Unwrap and pkg/errors' Cause
+func asAPIError(err error) (*APIError, bool) {
+	for err != nil {
+		if apiErr, ok := err.(*APIError); ok {
+			return apiErr, true
+		}
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapped.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapped.Cause()
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
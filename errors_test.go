@@ -0,0 +1,122 @@
+package spatially
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	httpmock "gopkg.in/jarcoal/httpmock.v1"
+)
+
+func TestAPIErrorFeatureNotFound(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	sdb, err := NewAPI(applicationCode, applicationKey)
+	if err != nil {
+		t.Error(err)
+	}
+	httpmock.RegisterResponder("GET", SpatiallyAPI+"/spatialdb/feature/1", httpmock.NewStringResponder(404, `{"message":"feature not found"}`))
+	feature := NewFeature()
+	err = feature.Get(sdb, "1")
+	if !IsNotFound(err) {
+		t.Fatal("Expected a not found error, got", err)
+	}
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatal("Expected an *APIError, got", err)
+	}
+	if apiErr.Method != "GET" || apiErr.Endpoint != "/spatialdb/feature/1" {
+		t.Error("Invalid error request", apiErr.Method, apiErr.Endpoint)
+	}
+	if apiErr.Message != "feature not found" {
+		t.Error("Invalid error message", apiErr.Message)
+	}
+	if string(apiErr.Body) != `{"message":"feature not found"}` {
+		t.Error("Invalid error body", string(apiErr.Body))
+	}
+	if IsUnauthorized(err) || IsRateLimited(err) {
+		t.Error("A not found error should not match other statuses")
+	}
+}
+
+func TestAPIErrorEmptyLayer(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	sdb, err := NewAPI(applicationCode, applicationKey)
+	if err != nil {
+		t.Error(err)
+	}
+	httpmock.RegisterResponder("GET", SpatiallyAPI+"/spatialdb/layer/1", httpmock.NewStringResponder(200, ""))
+	layer := NewLayer()
+	if err := layer.Get(sdb, "1"); !IsNotFound(err) {
+		t.Error("Expected a not found error for an empty layer response, got", err)
+	}
+}
+
+func TestAPIErrorDelete(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	sdb, err := NewAPI(applicationCode, applicationKey)
+	if err != nil {
+		t.Error(err)
+	}
+	httpmock.RegisterResponder("DELETE", SpatiallyAPI+"/spatialdb/layer/1", httpmock.NewStringResponder(429, "slow down"))
+	layer := NewLayer()
+	err = layer.Delete(sdb, "1")
+	if !IsRateLimited(err) {
+		t.Fatal("Expected a rate limited error, got", err)
+	}
+	if err.(*APIError).Message != "slow down" {
+		t.Error("Expected the plain text body as message, got", err.(*APIError).Message)
+	}
+}
+
+func TestAPIErrorATA(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	api, err := NewAPI(applicationCode, applicationKey)
+	if err != nil {
+		t.Error(err)
+	}
+	httpmock.RegisterResponder("POST", SpatiallyAPI+"/ads/science/ata", func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewJsonResponse(500, ataResponse{Message: "ata generation failed"})
+	})
+	_, err = NewATA(api, "POINT(-71.064156780428 42.35862883483673)", nil)
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatal("Expected an *APIError, got", err)
+	}
+	if apiErr.StatusCode != 500 || apiErr.Message != "ata generation failed" {
+		t.Error("Invalid error", apiErr)
+	}
+}
+
+func TestAPIErrorWrapped(t *testing.T) {
+	err := errors.Wrap(&APIError{StatusCode: http.StatusUnauthorized}, "get layers")
+	if !IsUnauthorized(err) {
+		t.Error("Expected a wrapped unauthorized error to be detected")
+	}
+	if IsNotFound(errors.New("not found")) {
+		t.Error("Plain errors are not API errors")
+	}
+	if IsNotFound(nil) {
+		t.Error("A nil error is not an API error")
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	body, _ := json.Marshal(requestError{"layer not found"})
+	err := &APIError{StatusCode: 404, Method: "GET", Endpoint: "/spatialdb/layer/1", Message: "layer not found", Body: body}
+	if err.Error() != "spatially GET /spatialdb/layer/1: 404 layer not found" {
+		t.Error("Invalid error message", err.Error())
+	}
+	err = &APIError{StatusCode: 503, Method: "GET", Endpoint: "/grid/stops"}
+	if err.Error() != "spatially GET /grid/stops: 503 Service Unavailable" {
+		t.Error("Invalid error message", err.Error())
+	}
+}
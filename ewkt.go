@@ -0,0 +1,34 @@
+package spatially
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// EWKTToGeometry converts an Extended Well Known Text shape, e.g. "SRID=3857;POINT(-7910975 5215494)", into
+// a geojson geometry and the SRID of its coordinates. Shapes without an SRID prefix are read as plain WKT
+// and return an SRID of 0. The coordinates are not reprojected, see ReprojectToWGS84.
+func EWKTToGeometry(ewkt string) (g *geojson.Geometry, srid int, err error) {
+	s := &scanner{raw: []byte(ewkt)}
+	s.skipWs()
+	if prefix := s.raw[s.i:]; len(prefix) >= 5 && strings.EqualFold(string(prefix[:5]), "SRID=") {
+		separator := bytes.IndexByte(prefix, ';')
+		if separator < 0 {
+			return nil, 0, s.syntaxError(len(s.raw), []string{"';'"}, "")
+		}
+		srid, err = strconv.Atoi(strings.TrimSpace(string(prefix[5:separator])))
+		if err != nil || srid < 0 {
+			return nil, 0, s.errorf(s.i+5, "invalid SRID '%s'", prefix[5:separator])
+		}
+		s.i += separator + 1
+	}
+	// errors are positioned in ewkt, SRID prefix included
+	g, err = s.scanGeom()
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, srid, nil
+}
@@ -0,0 +1,57 @@
+package spatially
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEWKTToGeometry(t *testing.T) {
+	for _, test := range []struct {
+		ewkt string
+		srid int
+		wkt  string
+	}{
+		{"SRID=3857;POINT(-7910975.5 5214980.5)", 3857, "POINT(-7910975.5 5214980.5)"},
+		{"srid=4326; POLYGON((0 0,1 0,1 1,0 0))", 4326, "POLYGON((0 0,1 0,1 1,0 0))"},
+		{"  SRID=32619;LINESTRING Z (330000 4690000 10,331000 4691000 12)", 32619, "LINESTRING Z (330000 4690000 10,331000 4691000 12)"},
+		{"POINT(1 2)", 0, "POINT(1 2)"},
+	} {
+		g, srid, err := EWKTToGeometry(test.ewkt)
+		if err != nil {
+			t.Error(test.ewkt, err)
+			continue
+		}
+		if srid != test.srid {
+			t.Error(test.ewkt, "expected SRID", test.srid, "got", srid)
+		}
+		wkt, err := GeometryToWKT(g)
+		if err != nil {
+			t.Error(err)
+		}
+		if wkt != test.wkt {
+			t.Error(test.ewkt, "expected", test.wkt, "got", wkt)
+		}
+	}
+	for _, ewkt := range []string{"SRID=3857POINT(1 2)", "SRID=abc;POINT(1 2)", "SRID=-1;POINT(1 2)", "SRID=4326;POINT(1)"} {
+		if _, _, err := EWKTToGeometry(ewkt); err == nil {
+			t.Error("Expected an error for", ewkt)
+		}
+	}
+}
+
+func TestNewFeatureFromEWKT(t *testing.T) {
+	feature, err := NewFeatureFromEWKT("SRID=3857;POINT(-7910975.5 5214980.5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feature.Geometry.IsPoint() {
+		t.Fatal("Expected a point")
+	}
+	lon, lat := feature.Geometry.Point[0], feature.Geometry.Point[1]
+	if math.Abs(lon+71.06550) > 1e-4 || math.Abs(lat-42.35948) > 1e-4 {
+		t.Error("Expected Boston, got", lon, lat)
+	}
+	if _, err := NewFeatureFromEWKT("SRID=2249;POINT(775000 2956000)"); err == nil {
+		t.Error("Expected an error for an unsupported SRID")
+	}
+}
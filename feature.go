@@ -2,10 +2,9 @@ package spatially
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"net/http"
 
 	geojson "github.com/paulmach/go.geojson"
 	"github.com/pkg/errors"
@@ -22,75 +21,33 @@ func NewFeatures() Features {
 type getFeaturesRequest struct {
 	LayerID           string             `json:"layer"`
 	SpatialConstraint *SpatialConstraint `json:"spatialConstraint"`
+	Filter            *Filter            `json:"filter,omitempty"`
+	PageOptions
 }
 
-// GetByLayer - Given a layer id, retrieves the features that belong to it and updates the slice receiver
+// GetByLayer - Given a layer id, retrieves the features that belong to it and updates the slice receiver.
+// The features are decoded as they arrive, following the pages of the response with a request per page,
+// see GetPage to read a single page and IterateFeatures to read them one at a time.
 func (f *Features) GetByLayer(db API, layerID string) (err error) {
-	requestBody := getFeaturesRequest{
-		LayerID: layerID,
-	}
-	j, err := json.Marshal(requestBody)
-	if err != nil {
-		return errors.Wrap(err, "get features by layer json marshal request body")
-	}
-	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/spatialdb/features", body)
-	if err != nil {
-		return errors.Wrap(err, "get features by layer prepare http request")
-	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
-	if err != nil {
-		return errors.Wrap(err, "get features by layer http post")
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Wrap(err, "get features by layer read response body")
-	}
-	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
-	}
-	if err = json.Unmarshal(responseBody, f); err != nil {
-		return errors.Wrap(err, "get features by layer parse response body json")
-	}
+	return f.GetByLayerContext(context.Background(), db, layerID)
+}
+
+// GetByLayerContext is GetByLayer with a context used to cancel the request or set its deadline
+func (f *Features) GetByLayerContext(ctx context.Context, db API, layerID string) (err error) {
+	_, err = f.read(newFeatureIterator(ctx, db, "get features by layer", layerID, nil, nil, nil))
 	return
 }
 
 // GetBySpatialConstraint - Given a layer id and spatial constraint object, retrieves all features that satisfay the constraint
-// and updates the slice receiver
-func (f *Features) GetBySpatialConstraint(db API, layerID string, spatialConstraint *SpatialConstraint) (err error) {
-	requestBody := getFeaturesRequest{
-		LayerID:           layerID,
-		SpatialConstraint: spatialConstraint,
-	}
-	j, err := json.Marshal(requestBody)
-	if err != nil {
-		return errors.Wrap(err, "get features by spatial constraint json marshal request body")
-	}
-	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/spatialdb/features", body)
-	if err != nil {
-		return errors.Wrap(err, "get features by spatial constraint prepare http request")
-	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
-	if err != nil {
-		return errors.Wrap(err, "get features by spatial constraint http post")
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Wrap(err, "get features by spatial constraint read response body")
-	}
-	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
-	}
-	if err = json.Unmarshal(responseBody, f); err != nil {
-		return errors.Wrap(err, "get features by spatial constraint parse response body json")
-	}
+// and updates the slice receiver. Filters on the features' properties narrow the features down further, all of them
+// having to select a feature. Every page is read, as with GetByLayer.
+func (f *Features) GetBySpatialConstraint(db API, layerID string, spatialConstraint *SpatialConstraint, filters ...*Filter) (err error) {
+	return f.GetBySpatialConstraintContext(context.Background(), db, layerID, spatialConstraint, filters...)
+}
+
+// GetBySpatialConstraintContext is GetBySpatialConstraint with a context used to cancel the request or set its deadline
+func (f *Features) GetBySpatialConstraintContext(ctx context.Context, db API, layerID string, spatialConstraint *SpatialConstraint, filters ...*Filter) (err error) {
+	_, err = f.read(newFeatureIterator(ctx, db, "get features by spatial constraint", layerID, spatialConstraint, nil, filters))
 	return
 }
 
@@ -119,15 +76,38 @@ func NewFeatureFromWKT(wkt string) (feature *Feature, err error) {
 	return
 }
 
+// NewFeatureFromEWKT will create a new feature given an Extended Well Known Text shape. The coordinates
+// are reprojected from the shape's SRID into the EPSG:4326 coordinates expected by the API. Shapes
+// without SRID are expected to be in EPSG:4326 already.
+func NewFeatureFromEWKT(ewkt string) (feature *Feature, err error) {
+	feature = &Feature{
+		Feature: &geojson.Feature{},
+	}
+	g, srid, err := EWKTToGeometry(ewkt)
+	if err != nil {
+		return feature, err
+	}
+	if srid != 0 {
+		if g, err = ReprojectToWGS84(g, srid); err != nil {
+			return feature, err
+		}
+	}
+	feature.Geometry = g
+	return
+}
+
 // Get - Given a feature id, retrieves the feature and updates the receiver
 func (f *Feature) Get(db API, id string) (err error) {
-	request, err := http.NewRequest("GET", SpatiallyAPI+"/spatialdb/feature/"+id, nil)
+	return f.GetContext(context.Background(), db, id)
+}
+
+// GetContext is Get with a context used to cancel the request or set its deadline
+func (f *Feature) GetContext(ctx context.Context, db API, id string) (err error) {
+	request, err := clientOf(db).NewRequestContext(ctx, "GET", "/spatialdb/feature/"+id, nil)
 	if err != nil {
 		return errors.Wrap(err, "get feature prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "get feature http get")
 	}
@@ -137,7 +117,7 @@ func (f *Feature) Get(db API, id string) (err error) {
 		return errors.Wrap(err, "get feature read response body")
 	}
 	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+		return newAPIError(request, resp, responseBody)
 	}
 	if err = json.Unmarshal(responseBody, f); err != nil {
 		return errors.Wrap(err, "get feature parse response body json")
@@ -151,8 +131,17 @@ type createFeatureRequest struct {
 }
 
 // Create - given a layer id, geometry and properties - creates the feature and updates the receiver with the created feature.
-// It also increases the layer feature count
+// It also increases the layer feature count. The geometry is validated or repaired first when the client is configured
+// WithGeometryValidation.
 func (f *Feature) Create(db API, layerID string, geometry *geojson.Geometry, properties map[string]interface{}) (err error) {
+	return f.CreateContext(context.Background(), db, layerID, geometry, properties)
+}
+
+// CreateContext is Create with a context used to cancel the request or set its deadline
+func (f *Feature) CreateContext(ctx context.Context, db API, layerID string, geometry *geojson.Geometry, properties map[string]interface{}) (err error) {
+	if geometry, err = clientOf(db).prepareGeometry(geometry); err != nil {
+		return errors.Wrap(err, "create feature geometry")
+	}
 	f.Geometry = geometry
 	f.Properties = properties
 	requestBody := createFeatureRequest{
@@ -164,13 +153,11 @@ func (f *Feature) Create(db API, layerID string, geometry *geojson.Geometry, pro
 		return errors.Wrap(err, "create feature json marshal request body")
 	}
 	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/spatialdb/feature", body)
+	request, err := clientOf(db).NewRequestContext(ctx, "POST", "/spatialdb/feature", body)
 	if err != nil {
 		return errors.Wrap(err, "create feature prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "create feature http post")
 	}
@@ -180,7 +167,7 @@ func (f *Feature) Create(db API, layerID string, geometry *geojson.Geometry, pro
 		return errors.Wrap(err, "create feature read response body")
 	}
 	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+		return newAPIError(request, resp, responseBody)
 	}
 	if err = json.Unmarshal(responseBody, f); err != nil {
 		return errors.Wrap(err, "create feature parse response body json")
@@ -194,6 +181,11 @@ type updateFeatureRequest struct {
 
 // Update - Given a feature id and properties, it updates the feature and receiver
 func (f *Feature) Update(db API, id string, properties map[string]interface{}) (err error) {
+	return f.UpdateContext(context.Background(), db, id, properties)
+}
+
+// UpdateContext is Update with a context used to cancel the request or set its deadline
+func (f *Feature) UpdateContext(ctx context.Context, db API, id string, properties map[string]interface{}) (err error) {
 	requestBody := updateFeatureRequest{
 		Properties: properties,
 	}
@@ -202,13 +194,11 @@ func (f *Feature) Update(db API, id string, properties map[string]interface{}) (
 		return errors.Wrap(err, "update feature json marshal request body")
 	}
 	body := bytes.NewReader(j)
-	request, err := http.NewRequest("PUT", SpatiallyAPI+"/spatialdb/feature/"+id, body)
+	request, err := clientOf(db).NewRequestContext(ctx, "PUT", "/spatialdb/feature/"+id, body)
 	if err != nil {
 		return errors.Wrap(err, "update feature prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "update feature http put")
 	}
@@ -218,7 +208,7 @@ func (f *Feature) Update(db API, id string, properties map[string]interface{}) (
 		return errors.Wrap(err, "update feature read response body")
 	}
 	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+		return newAPIError(request, resp, responseBody)
 	}
 	if err = json.Unmarshal(responseBody, f); err != nil {
 		return errors.Wrap(err, "update feature parse response body json")
@@ -226,21 +216,35 @@ func (f *Feature) Update(db API, id string, properties map[string]interface{}) (
 	return
 }
 
-// Delete - Given a feature id, it deletes the feature and decreases the layer's feature count
+// Delete - Given a feature id, it deletes the feature and decreases the layer's feature count. The receiver is
+// updated with the deleted feature when Spatially responds with it.
 func (f *Feature) Delete(db API, id string) (err error) {
-	request, err := http.NewRequest("DELETE", SpatiallyAPI+"/spatialdb/feature/"+id, nil)
+	return f.DeleteContext(context.Background(), db, id)
+}
+
+// DeleteContext is Delete with a context used to cancel the request or set its deadline
+func (f *Feature) DeleteContext(ctx context.Context, db API, id string) (err error) {
+	request, err := clientOf(db).NewRequestContext(ctx, "DELETE", "/spatialdb/feature/"+id, nil)
 	if err != nil {
 		return errors.Wrap(err, "delete feature prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "delete feature http delete")
 	}
 	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "delete feature read response body")
+	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("There was an unexpected error deleting feature ID: %v", id)
+		return newAPIError(request, resp, responseBody)
+	}
+	if len(bytes.TrimSpace(responseBody)) == 0 {
+		return
+	}
+	if err = json.Unmarshal(responseBody, f); err != nil {
+		return errors.Wrap(err, "delete feature parse response body json")
 	}
 	return
 }
@@ -1,10 +1,13 @@
 package spatially
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	geojson "github.com/paulmach/go.geojson"
 	"github.com/pborman/uuid"
@@ -306,3 +309,39 @@ func TestDeleteFeature(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestGetFeaturesContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	})
+	defer server.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	features := NewFeatures()
+	err := features.GetByLayerContext(ctx, sdb, uuid.NewUUID().String())
+	if err == nil {
+		t.Fatal("Expected the request to be canceled")
+	}
+	if !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Error("Expected a deadline exceeded error, got", err)
+	}
+}
+
+func TestDeleteFeatureResponse(t *testing.T) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+		feature.ID = "deleted"
+		feature.SetProperty("name", "Starbucks")
+		json.NewEncoder(w).Encode(feature)
+	})
+	defer server.Close()
+	feature := NewFeature()
+	if err := feature.Delete(sdb, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if feature.ID != "deleted" || feature.PropertyMustString("name") != "Starbucks" {
+		t.Errorf("Expected the deleted feature, got %+v", feature.Feature)
+	}
+}
@@ -0,0 +1,272 @@
+package spatially
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// FilterOperator is the operation of a Filter, sent to the API by name
+type FilterOperator string
+
+// Filter operators
+const (
+	FilterEq     FilterOperator = "eq"
+	FilterNeq    FilterOperator = "neq"
+	FilterGt     FilterOperator = "gt"
+	FilterLt     FilterOperator = "lt"
+	FilterIn     FilterOperator = "in"
+	FilterLike   FilterOperator = "like"
+	FilterExists FilterOperator = "exists"
+	FilterAnd    FilterOperator = "and"
+	FilterOr     FilterOperator = "or"
+	FilterNot    FilterOperator = "not"
+)
+
+// Filter is a condition on the properties of features, built with Eq, Neq, Gt, Lt, In, Like, Exists, And,
+// Or and Not, e.g. And(Eq("brand", "Starbucks"), Gt("sales", 1000000)). Comparisons are false for
+// features without the property. Numbers are compared by value whatever their Go type, and strings
+// compare in byte order.
+type Filter struct {
+	Op       FilterOperator `json:"op"`
+	Property string         `json:"property,omitempty"`
+	Value    interface{}    `json:"value,omitempty"`
+	Values   []interface{}  `json:"values,omitempty"`
+	Filters  []*Filter      `json:"filters,omitempty"`
+}
+
+// Eq selects features whose property equals value
+func Eq(property string, value interface{}) *Filter {
+	return &Filter{Op: FilterEq, Property: property, Value: value}
+}
+
+// Neq selects features which have the property with another value than value
+func Neq(property string, value interface{}) *Filter {
+	return &Filter{Op: FilterNeq, Property: property, Value: value}
+}
+
+// Gt selects features whose property is greater than value
+func Gt(property string, value interface{}) *Filter {
+	return &Filter{Op: FilterGt, Property: property, Value: value}
+}
+
+// Lt selects features whose property is less than value
+func Lt(property string, value interface{}) *Filter {
+	return &Filter{Op: FilterLt, Property: property, Value: value}
+}
+
+// In selects features whose property equals one of values
+func In(property string, values ...interface{}) *Filter {
+	return &Filter{Op: FilterIn, Property: property, Values: values}
+}
+
+// Like selects features whose property is a string matching the SQL pattern, where % matches any run of
+// characters and _ any single character, e.g. Like("name", "Starbucks%")
+func Like(property string, pattern string) *Filter {
+	return &Filter{Op: FilterLike, Property: property, Value: pattern}
+}
+
+// Exists selects features which have the property, whatever its value
+func Exists(property string) *Filter {
+	return &Filter{Op: FilterExists, Property: property}
+}
+
+// And selects features selected by all of the filters
+func And(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterAnd, Filters: filters}
+}
+
+// Or selects features selected by any of the filters
+func Or(filters ...*Filter) *Filter {
+	return &Filter{Op: FilterOr, Filters: filters}
+}
+
+// Not selects features the filter doesn't select
+func Not(filter *Filter) *Filter {
+	return &Filter{Op: FilterNot, Filters: []*Filter{filter}}
+}
+
+// validate checks the filter and the filters it's made of before it's sent
+func (f *Filter) validate() error {
+	if f == nil {
+		return fmt.Errorf("nil filter")
+	}
+	switch f.Op {
+	case FilterEq, FilterNeq, FilterGt, FilterLt, FilterIn, FilterLike, FilterExists:
+		if f.Property == "" {
+			return fmt.Errorf("%s filter without a property", f.Op)
+		}
+		if _, ok := f.Value.(string); f.Op == FilterLike && !ok {
+			return fmt.Errorf("like filter on %s needs a string pattern, got %T", f.Property, f.Value)
+		}
+	case FilterAnd, FilterOr:
+		if len(f.Filters) == 0 {
+			return fmt.Errorf("%s filter without filters", f.Op)
+		}
+	case FilterNot:
+		if len(f.Filters) != 1 {
+			return fmt.Errorf("not filter needs 1 filter, got %d", len(f.Filters))
+		}
+	default:
+		return fmt.Errorf("unknown filter operator %q", f.Op)
+	}
+	for _, filter := range f.Filters {
+		if err := filter.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the filter selects a feature with the properties, evaluating it locally the way
+// the API does, e.g. to check features received earlier. Invalid filters match nothing.
+func (f *Filter) Matches(properties map[string]interface{}) bool {
+	if f.validate() != nil {
+		return false
+	}
+	return f.matches(properties, f.likePatterns(nil))
+}
+
+// likePatterns compiles the LIKE patterns of the filter and the filters it's made of, by filter
+func (f *Filter) likePatterns(patterns map[*Filter]*regexp.Regexp) map[*Filter]*regexp.Regexp {
+	if patterns == nil {
+		patterns = map[*Filter]*regexp.Regexp{}
+	}
+	if f.Op == FilterLike && patterns[f] == nil {
+		patterns[f] = likePattern(f.Value.(string))
+	}
+	for _, filter := range f.Filters {
+		filter.likePatterns(patterns)
+	}
+	return patterns
+}
+
+// matches evaluates a valid filter with the regular expressions of its LIKE patterns
+func (f *Filter) matches(properties map[string]interface{}, patterns map[*Filter]*regexp.Regexp) bool {
+	switch f.Op {
+	case FilterAnd:
+		for _, filter := range f.Filters {
+			if !filter.matches(properties, patterns) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, filter := range f.Filters {
+			if filter.matches(properties, patterns) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return !f.Filters[0].matches(properties, patterns)
+	}
+	value, exists := properties[f.Property]
+	if !exists {
+		return false
+	}
+	switch f.Op {
+	case FilterEq:
+		return filterEqual(value, f.Value)
+	case FilterNeq:
+		return !filterEqual(value, f.Value)
+	case FilterGt:
+		order, ok := filterCompare(value, f.Value)
+		return ok && order > 0
+	case FilterLt:
+		order, ok := filterCompare(value, f.Value)
+		return ok && order < 0
+	case FilterIn:
+		for _, v := range f.Values {
+			if filterEqual(value, v) {
+				return true
+			}
+		}
+		return false
+	case FilterLike:
+		s, ok := value.(string)
+		return ok && patterns[f].MatchString(s)
+	}
+	return true
+}
+
+// filterNumber returns the value of a number of any Go type, as decoded from JSON or given to a filter
+func filterNumber(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+func filterEqual(a, b interface{}) bool {
+	if order, ok := filterCompare(a, b); ok {
+		return order == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// filterCompare orders two numbers or two strings, it's not ok for other values
+func filterCompare(a, b interface{}) (int, bool) {
+	if x, ok := filterNumber(a); ok {
+		y, ok := filterNumber(b)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// likePattern compiles an SQL LIKE pattern into a regular expression matching whole strings
+func likePattern(pattern string) *regexp.Regexp {
+	var expression strings.Builder
+	expression.WriteString("(?s)^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			expression.WriteString(".*")
+		case '_':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.MustCompile(expression.String())
+}
+
+// Filter returns the features the filter selects, see Filter.Matches. The filter is checked and its
+// patterns compiled once for all the features.
+func (f Features) Filter(filter *Filter) Features {
+	filtered := NewFeatures()
+	if filter.validate() != nil {
+		return filtered
+	}
+	patterns := filter.likePatterns(nil)
+	for _, feature := range f {
+		if feature != nil && feature.Feature != nil && filter.matches(feature.Properties, patterns) {
+			filtered = append(filtered, feature)
+		}
+	}
+	return filtered
+}
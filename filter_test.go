@@ -0,0 +1,129 @@
+package spatially
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestFilterJSON(t *testing.T) {
+	for _, test := range []struct {
+		filter   *Filter
+		expected string
+	}{
+		{Eq("brand", "Starbucks"), `{"op":"eq","property":"brand","value":"Starbucks"}`},
+		{Neq("open", false), `{"op":"neq","property":"open","value":false}`},
+		{Gt("sales", 0), `{"op":"gt","property":"sales","value":0}`},
+		{Lt("rank", 10.5), `{"op":"lt","property":"rank","value":10.5}`},
+		{In("state", "MA", "NH"), `{"op":"in","property":"state","values":["MA","NH"]}`},
+		{Like("name", "Star%"), `{"op":"like","property":"name","value":"Star%"}`},
+		{Exists("phone"), `{"op":"exists","property":"phone"}`},
+		{
+			And(Eq("brand", "Starbucks"), Or(Exists("drive"), Not(Lt("seats", 20)))),
+			`{"op":"and","filters":[{"op":"eq","property":"brand","value":"Starbucks"},{"op":"or","filters":[{"op":"exists","property":"drive"},{"op":"not","filters":[{"op":"lt","property":"seats","value":20}]}]}]}`,
+		},
+	} {
+		j, err := json.Marshal(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(j) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, j)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	var properties map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"brand":"Starbucks","name":"Starbucks Boston (50% off)","sales":1200000,"open":true,"seats":12,"phone":null}`), &properties); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		filter  *Filter
+		matches bool
+	}{
+		{Eq("brand", "Starbucks"), true},
+		{Eq("brand", "Dunkin"), false},
+		{Eq("seats", 12), true},
+		{Eq("seats", uint8(12)), true},
+		{Eq("open", true), true},
+		{Eq("missing", nil), false},
+		{Eq("phone", nil), true},
+		{Neq("brand", "Dunkin"), true},
+		{Neq("missing", "Dunkin"), false},
+		{Gt("sales", 1000000), true},
+		{Gt("sales", 2e6), false},
+		{Gt("brand", "Dunkin"), true},
+		{Gt("brand", 1), false},
+		{Lt("seats", 20), true},
+		{Lt("open", 20), false},
+		{In("brand", "Dunkin", "Starbucks"), true},
+		{In("seats", 10, 11), false},
+		{Like("name", "Starbucks%"), true},
+		{Like("name", "%50% off)"), true},
+		{Like("name", "Starbucks Bosto_ %"), true},
+		{Like("name", "starbucks%"), false},
+		{Like("seats", "1%"), false},
+		{Exists("phone"), true},
+		{Exists("drive"), false},
+		{And(Eq("brand", "Starbucks"), Gt("seats", 10)), true},
+		{And(Eq("brand", "Starbucks"), Gt("seats", 20)), false},
+		{Or(Eq("brand", "Dunkin"), Gt("seats", 10)), true},
+		{Or(Eq("brand", "Dunkin"), Gt("seats", 20)), false},
+		{Not(Exists("drive")), true},
+		{Not(Eq("brand", "Starbucks")), false},
+		// invalid filters match nothing
+		{And(), false},
+		{Not(nil), false},
+		{&Filter{Op: FilterLike, Property: "name", Value: 5}, false},
+		{&Filter{Op: "near", Property: "brand"}, false},
+	} {
+		if matches := test.filter.Matches(properties); matches != test.matches {
+			j, _ := json.Marshal(test.filter)
+			t.Errorf("Expected %s to match: %t", j, test.matches)
+		}
+	}
+}
+
+func TestGetFeaturesFilter(t *testing.T) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		var request getFeaturesRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if request.SpatialConstraint == nil || request.Filter == nil || request.Filter.Op != FilterAnd || len(request.Filter.Filters) != 2 {
+			j, _ := json.Marshal(request)
+			t.Error("Expected a spatial constraint and both filters, got", string(j))
+		}
+		var features []*geojson.Feature
+		for _, brand := range []string{"Starbucks", "Dunkin"} {
+			feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+			feature.SetProperty("brand", brand)
+			features = append(features, feature)
+		}
+		json.NewEncoder(w).Encode(features)
+	})
+	defer server.Close()
+	features := NewFeatures()
+	constraint := &SpatialConstraint{WKT: "POINT(-71.06 42.35)", Radius: 1000}
+	if err := features.GetBySpatialConstraint(sdb, "layer", constraint, Eq("brand", "Starbucks"), Exists("phone")); err != nil {
+		t.Fatal(err)
+	}
+	if starbucks := features.Filter(Eq("brand", "Starbucks")); len(starbucks) != 1 || starbucks[0].PropertyMustString("brand") != "Starbucks" {
+		t.Errorf("Expected 1 feature, got %+v", starbucks)
+	}
+	if coffee := features.Filter(Or(Like("brand", "Star%"), Not(Like("brand", "Star%")))); len(coffee) != 2 {
+		t.Errorf("Expected 2 features, got %+v", coffee)
+	}
+	if dunkin := features.Filter(And(Like("brand", "D_nkin"), Neq("brand", "Starbucks"))); len(dunkin) != 1 {
+		t.Errorf("Expected 1 feature, got %+v", dunkin)
+	}
+	if invalid := features.Filter(Or()); len(invalid) != 0 {
+		t.Errorf("Expected no features for an invalid filter, got %+v", invalid)
+	}
+	if err := features.GetBySpatialConstraint(sdb, "layer", constraint, Or()); err == nil {
+		t.Error("Expected an error for an invalid filter")
+	}
+}
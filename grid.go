@@ -2,10 +2,10 @@ package spatially
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 
 	"github.com/Spatially/go-geometry"
@@ -27,6 +27,11 @@ type GridPopulation struct {
 
 //
 func Population(api API, locationWKT string, buffer int) (pop *GridPopulation, err error) {
+	return PopulationContext(context.Background(), api, locationWKT, buffer)
+}
+
+// PopulationContext is Population with a context used to cancel the request or set its deadline
+func PopulationContext(ctx context.Context, api API, locationWKT string, buffer int) (pop *GridPopulation, err error) {
 	type populationResponse struct {
 		Result *GridPopulation `json:"result"`
 	}
@@ -34,18 +39,15 @@ func Population(api API, locationWKT string, buffer int) (pop *GridPopulation, e
 	if err != nil {
 		return pop, errors.Wrap(err, "feature from wkt")
 	}
-	if feature.Geometry.Type != geojson.GeometryPoint {
+	if feature.Geometry.Type != geojson.GeometryPoint || len(feature.Geometry.Point) == 0 {
 		return pop, errors.New("location wkt must be a point")
 	}
 	lon, lat := feature.Geometry.Point[0], feature.Geometry.Point[1]
-	url := fmt.Sprintf(SpatiallyAPI+"/grid/pop/point?lat=%v&lon=%v&radius=%v", lat, lon, buffer)
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := clientOf(api).NewRequestContext(ctx, "GET", fmt.Sprintf("/grid/pop/point?lat=%v&lon=%v&radius=%v", lat, lon, buffer), nil)
 	if err != nil {
 		return pop, errors.Wrap(err, "prepare http request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, request)
 	if err != nil {
 		return pop, errors.Wrap(err, "http get")
 	}
@@ -55,7 +57,7 @@ func Population(api API, locationWKT string, buffer int) (pop *GridPopulation, e
 		return pop, errors.Wrap(err, "read response body")
 	}
 	if resp.StatusCode != 200 {
-		return pop, api.Error(responseBody)
+		return pop, newAPIError(request, resp, responseBody)
 	}
 	response := populationResponse{}
 	if err = json.Unmarshal(responseBody, &response); err != nil {
@@ -66,6 +68,11 @@ func Population(api API, locationWKT string, buffer int) (pop *GridPopulation, e
 
 //
 func TradeAreaMarketSize(api API, tradeArea *ATA) (pop *GridPopulation, err error) {
+	return TradeAreaMarketSizeContext(context.Background(), api, tradeArea)
+}
+
+// TradeAreaMarketSizeContext is TradeAreaMarketSize with a context used to cancel the request or set its deadline
+func TradeAreaMarketSizeContext(ctx context.Context, api API, tradeArea *ATA) (pop *GridPopulation, err error) {
 	type tradeAreaMarketSizeRequest struct {
 		FeatureCollection *geometry.FeatureCollection `json:"featureCollection"`
 	}
@@ -75,13 +82,11 @@ func TradeAreaMarketSize(api API, tradeArea *ATA) (pop *GridPopulation, err erro
 		return nil, errors.Wrap(err, "request to json")
 	}
 	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/grid/pop", body)
+	request, err := clientOf(api).NewRequestContext(ctx, "POST", "/grid/pop", body)
 	if err != nil {
 		return nil, errors.Wrap(err, "ata request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, idempotent(request))
 	if err != nil {
 		return nil, errors.Wrap(err, "request do")
 	}
@@ -90,6 +95,9 @@ func TradeAreaMarketSize(api API, tradeArea *ATA) (pop *GridPopulation, err erro
 	if err != nil {
 		return nil, errors.Wrap(err, "read ata request response body")
 	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(request, resp, responseBody)
+	}
 	pop = &GridPopulation{}
 	if err := json.Unmarshal(responseBody, pop); err != nil {
 		return nil, errors.Wrap(err, "json unmarshal")
@@ -110,17 +118,19 @@ type HourlyStops struct {
 
 //
 func PopularTimes(api API, locationWKT string, buffer int) (pt *GridPopularTimes, err error) {
+	return PopularTimesContext(context.Background(), api, locationWKT, buffer)
+}
+
+// PopularTimesContext is PopularTimes with a context used to cancel the request or set its deadline
+func PopularTimesContext(ctx context.Context, api API, locationWKT string, buffer int) (pt *GridPopularTimes, err error) {
 	v := &url.Values{}
 	v.Add("wkt", locationWKT)
 	v.Add("radius", fmt.Sprintf("%v", buffer))
-	url := fmt.Sprintf(SpatiallyAPI+"/grid/stops?%v", v.Encode())
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := clientOf(api).NewRequestContext(ctx, "GET", "/grid/stops?"+v.Encode(), nil)
 	if err != nil {
 		return pt, errors.Wrap(err, "prepare http request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, request)
 	if err != nil {
 		return pt, errors.Wrap(err, "http get")
 	}
@@ -130,7 +140,7 @@ func PopularTimes(api API, locationWKT string, buffer int) (pt *GridPopularTimes
 		return pt, errors.Wrap(err, "read response body")
 	}
 	if resp.StatusCode != 200 {
-		return pt, api.Error(responseBody)
+		return pt, newAPIError(request, resp, responseBody)
 	}
 	pt = &GridPopularTimes{}
 	if err = json.Unmarshal(responseBody, pt); err != nil {
@@ -158,21 +168,24 @@ type Distances struct {
 
 //
 func DistanceSensitivity(api API, locationWKT string, buffer int) (ds *GridDistanceSensitivity, err error) {
+	return DistanceSensitivityContext(context.Background(), api, locationWKT, buffer)
+}
+
+// DistanceSensitivityContext is DistanceSensitivity with a context used to cancel the request or set its deadline
+func DistanceSensitivityContext(ctx context.Context, api API, locationWKT string, buffer int) (ds *GridDistanceSensitivity, err error) {
 	feature, err := NewFeatureFromWKT(locationWKT)
 	if err != nil {
 		return ds, errors.Wrap(err, "feature from wkt")
 	}
-	if feature.Geometry.Type != geojson.GeometryPoint {
+	if feature.Geometry.Type != geojson.GeometryPoint || len(feature.Geometry.Point) == 0 {
 		return ds, errors.New("location wkt must be a point")
 	}
 	lon, lat := feature.Geometry.Point[0], feature.Geometry.Point[1]
-	request, err := http.NewRequest("GET", fmt.Sprintf(SpatiallyAPI+"/grid/distance?lat=%v&lon=%v&radius=%v", lat, lon, buffer), nil)
+	request, err := clientOf(api).NewRequestContext(ctx, "GET", fmt.Sprintf("/grid/distance?lat=%v&lon=%v&radius=%v", lat, lon, buffer), nil)
 	if err != nil {
 		return ds, errors.Wrap(err, "prepare http request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, request)
 	if err != nil {
 		return ds, errors.Wrap(err, "http get")
 	}
@@ -182,7 +195,7 @@ func DistanceSensitivity(api API, locationWKT string, buffer int) (ds *GridDista
 		return ds, errors.Wrap(err, "read response body")
 	}
 	if resp.StatusCode != 200 {
-		return ds, api.Error(responseBody)
+		return ds, newAPIError(request, resp, responseBody)
 	}
 	ds = &GridDistanceSensitivity{}
 	if err = json.Unmarshal(responseBody, ds); err != nil {
@@ -207,21 +220,24 @@ type PopulationType struct {
 
 //
 func Demographics(api API, locationWKT string, buffer int) (gd *GridDemographics, err error) {
+	return DemographicsContext(context.Background(), api, locationWKT, buffer)
+}
+
+// DemographicsContext is Demographics with a context used to cancel the request or set its deadline
+func DemographicsContext(ctx context.Context, api API, locationWKT string, buffer int) (gd *GridDemographics, err error) {
 	feature, err := NewFeatureFromWKT(locationWKT)
 	if err != nil {
 		return gd, errors.Wrap(err, "feature from wkt")
 	}
-	if feature.Geometry.Type != geojson.GeometryPoint {
+	if feature.Geometry.Type != geojson.GeometryPoint || len(feature.Geometry.Point) == 0 {
 		return gd, errors.New("location wkt must be a point")
 	}
 	lon, lat := feature.Geometry.Point[0], feature.Geometry.Point[1]
-	request, err := http.NewRequest("GET", fmt.Sprintf(SpatiallyAPI+"/grid/highlights?lat=%v&lon=%v&radius=%v", lat, lon, buffer), nil)
+	request, err := clientOf(api).NewRequestContext(ctx, "GET", fmt.Sprintf("/grid/highlights?lat=%v&lon=%v&radius=%v", lat, lon, buffer), nil)
 	if err != nil {
 		return gd, errors.Wrap(err, "prepare http request")
 	}
-	api.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(api, request)
 	if err != nil {
 		return gd, errors.Wrap(err, "http get")
 	}
@@ -231,7 +247,7 @@ func Demographics(api API, locationWKT string, buffer int) (gd *GridDemographics
 		return gd, errors.Wrap(err, "read response body")
 	}
 	if resp.StatusCode != 200 {
-		return gd, api.Error(responseBody)
+		return gd, newAPIError(request, resp, responseBody)
 	}
 	type demographicsResponse struct {
 		Result *GridDemographics `json:"result"`
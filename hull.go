@@ -0,0 +1,502 @@
+package spatially
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// ConcaveHullOptions sets how far ConcaveHull cuts into the convex hull of the points
+type ConcaveHullOptions struct {
+	// MaxEdgeLength is the length in meters above which the edges of the hull are cut into. When it's 0
+	// LengthRatio is used instead.
+	MaxEdgeLength float64
+	// LengthRatio places the length above which edges are cut into between the shortest and the longest
+	// edge of the Delaunay triangulation of the points: 0 cuts in as far as possible and 1 keeps the
+	// convex hull
+	LengthRatio float64
+}
+
+// ConvexHull returns the smallest convex Polygon containing the points, e.g. to outline customer
+// addresses. The hull is computed on a plane tangent to the WGS84 ellipsoid at the center of the points
+// and its vertices are points as they are, without Z values. There must be 3 points which aren't all on a
+// line.
+func ConvexHull(points [][]float64) (*geojson.Geometry, error) {
+	projection, originals, projected, err := hullPoints(points)
+	if err != nil {
+		return nil, err
+	}
+	// Andrew's monotone chain: the lower then the upper hull of the points sorted by x
+	sort.Slice(projected, func(i, j int) bool {
+		if projected[i][0] != projected[j][0] {
+			return projected[i][0] < projected[j][0]
+		}
+		return projected[i][1] < projected[j][1]
+	})
+	hull := make(ring, 0, len(projected)+1)
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, v := range projected {
+			for len(hull) >= start+2 && cross(hull[len(hull)-1].sub(hull[len(hull)-2]), v.sub(hull[len(hull)-1])) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, v)
+		}
+		// the last vertex of a chain is the first of the next one
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(projected)-1; i < j; i, j = i+1, j-1 {
+			projected[i], projected[j] = projected[j], projected[i]
+		}
+	}
+	if len(hull) < 3 {
+		return nil, fmt.Errorf("can't outline points which are all on a line")
+	}
+	hull = append(hull, hull[0])
+	return unprojectExactly(projection, originals, []planarPolygon{{hull}}), nil
+}
+
+// ConcaveHull returns a Polygon containing the points which follows them more closely than their convex
+// hull, computed as a χ-shape: the longest edges on the outside of the Delaunay triangulation of the
+// points are removed with their triangles while they're longer than the length set by options and the
+// polygon stays in one piece. Every point is in the polygon, on its boundary or inside, and the polygon
+// has no holes. Like ConvexHull it's computed on a plane tangent to the WGS84 ellipsoid and there must be
+// 3 points which aren't all on a line. Nil options cut in as far as possible.
+func ConcaveHull(points [][]float64, options *ConcaveHullOptions) (*geojson.Geometry, error) {
+	if options == nil {
+		options = &ConcaveHullOptions{}
+	}
+	if options.MaxEdgeLength < 0 || math.IsNaN(options.MaxEdgeLength) {
+		return nil, fmt.Errorf("concave hull edge length must not be negative, got %v", options.MaxEdgeLength)
+	}
+	if !(options.LengthRatio >= 0 && options.LengthRatio <= 1) {
+		return nil, fmt.Errorf("concave hull length ratio must be between 0 and 1, got %v", options.LengthRatio)
+	}
+	projection, originals, projected, err := hullPoints(points)
+	if err != nil {
+		return nil, err
+	}
+	d := triangulate(projected)
+	if d == nil {
+		return nil, fmt.Errorf("can't outline points which are all on a line")
+	}
+	threshold := options.MaxEdgeLength
+	if threshold == 0 {
+		shortest, longest := math.Inf(1), 0.0
+		for e, twin := range d.halfedges {
+			if twin < e {
+				length := d.length(e)
+				shortest, longest = math.Min(shortest, length), math.Max(longest, length)
+			}
+		}
+		threshold = shortest + options.LengthRatio*(longest-shortest)
+	}
+	return unprojectExactly(projection, originals, []planarPolygon{{d.chiShape(threshold)}}), nil
+}
+
+// ConvexHull returns the convex hull of the vertices of the features' geometries, see ConvexHull
+func (f Features) ConvexHull() (*geojson.Geometry, error) {
+	return ConvexHull(f.vertices())
+}
+
+// ConcaveHull returns the concave hull of the vertices of the features' geometries, e.g. of the features
+// returned by GetBySpatialConstraint to compare with an ATA, see ConcaveHull
+func (f Features) ConcaveHull(options *ConcaveHullOptions) (*geojson.Geometry, error) {
+	return ConcaveHull(f.vertices(), options)
+}
+
+func (f Features) vertices() [][]float64 {
+	var vertices [][]float64
+	for _, feature := range f {
+		if feature == nil || feature.Feature == nil {
+			continue
+		}
+		points, lines, polygons := flatten(feature.Geometry)
+		vertices = append(vertices, points...)
+		for _, line := range lines {
+			vertices = append(vertices, line...)
+		}
+		for _, polygon := range polygons {
+			for _, r := range polygon {
+				vertices = append(vertices, r...)
+			}
+		}
+	}
+	return vertices
+}
+
+// hullPoints returns the projection centered on the points, the points by projected point and the
+// distinct projected points
+func hullPoints(points [][]float64) (localProjection, map[vec][]float64, []vec, error) {
+	for _, p := range points {
+		if len(p) < 2 {
+			return localProjection{}, nil, nil, fmt.Errorf("point must be at least 2d. got %d elements", len(p))
+		}
+		if math.IsNaN(p[0]) || math.IsInf(p[0], 0) || math.IsNaN(p[1]) || math.IsInf(p[1], 0) {
+			return localProjection{}, nil, nil, fmt.Errorf("invalid coordinate %v", p)
+		}
+	}
+	bbox := BBox(geojson.NewMultiPointGeometry(points...))
+	if bbox == nil {
+		return localProjection{}, nil, nil, fmt.Errorf("can't outline fewer than 3 points, got %d", len(points))
+	}
+	projection := newLocalProjection(bbox)
+	originals := map[vec][]float64{}
+	var projected []vec
+	for _, p := range points {
+		v := projection.forward(p)
+		if _, ok := originals[v]; !ok {
+			originals[v] = p[:2]
+			projected = append(projected, v)
+		}
+	}
+	if len(projected) < 3 {
+		return localProjection{}, nil, nil, fmt.Errorf("can't outline fewer than 3 points, got %d distinct", len(projected))
+	}
+	return projection, originals, projected, nil
+}
+
+// delaunay is a Delaunay triangulation built by sweeping a hull around a seed triangle, visiting the points
+// by distance to its center and flipping the edges which aren't Delaunay. Triangles are triples of
+// halfedges: halfedge e goes from point triangles[e] to the next point of its triangle, and halfedges[e]
+// is the opposite halfedge of the neighboring triangle, -1 on the outside of the triangulation.
+type delaunay struct {
+	points    []vec
+	triangles []int
+	halfedges []int
+
+	// the hull is a linked list of points, hullTri is the halfedge inside the hull edge starting at a point
+	hullStart                   int
+	hullPrev, hullNext, hullTri []int
+	// hullHash finds hull points by their angle from the center
+	hullHash []int
+	center   vec
+	edges    []int
+}
+
+func nextHalfedge(e int) int {
+	if e%3 == 2 {
+		return e - 2
+	}
+	return e + 1
+}
+
+// triangulate returns the Delaunay triangulation of distinct points, nil when they're all on a line
+func triangulate(points []vec) *delaunay {
+	n := len(points)
+	d := &delaunay{points: points}
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range points {
+		bbox[0], bbox[1] = math.Min(bbox[0], p[0]), math.Min(bbox[1], p[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], p[0]), math.Max(bbox[3], p[1])
+	}
+	middle := vec{(bbox[0] + bbox[2]) / 2, (bbox[1] + bbox[3]) / 2}
+
+	// the seed triangle is the point closest to the middle, the point closest to it and the point making
+	// the smallest circumcircle with them
+	i0, i1, i2 := -1, -1, -1
+	closest := math.Inf(1)
+	for i, p := range points {
+		if dd := squaredDistance(middle, p); dd < closest {
+			i0, closest = i, dd
+		}
+	}
+	closest = math.Inf(1)
+	for i, p := range points {
+		if dd := squaredDistance(points[i0], p); i != i0 && dd < closest {
+			i1, closest = i, dd
+		}
+	}
+	smallest := math.Inf(1)
+	for i, p := range points {
+		if i == i0 || i == i1 {
+			continue
+		}
+		if r := circumradius(points[i0], points[i1], p); r < smallest {
+			i2, smallest = i, r
+		}
+	}
+	if i2 == -1 {
+		return nil
+	}
+	if orient(points[i0], points[i1], points[i2]) {
+		i1, i2 = i2, i1
+	}
+	d.center = circumcenter(points[i0], points[i1], points[i2])
+
+	ids := make([]int, n)
+	distances := make([]float64, n)
+	for i, p := range points {
+		ids[i], distances[i] = i, squaredDistance(d.center, p)
+	}
+	sort.Slice(ids, func(i, j int) bool { return distances[ids[i]] < distances[ids[j]] })
+
+	d.hullPrev, d.hullNext, d.hullTri = make([]int, n), make([]int, n), make([]int, n)
+	d.hullHash = make([]int, int(math.Ceil(math.Sqrt(float64(n)))))
+	for i := range d.hullHash {
+		d.hullHash[i] = -1
+	}
+	d.hullStart = i0
+	d.hullNext[i0], d.hullPrev[i2] = i1, i1
+	d.hullNext[i1], d.hullPrev[i0] = i2, i2
+	d.hullNext[i2], d.hullPrev[i1] = i0, i0
+	d.hullTri[i0], d.hullTri[i1], d.hullTri[i2] = 0, 1, 2
+	for _, i := range []int{i0, i1, i2} {
+		d.hullHash[d.hashKey(points[i])] = i
+	}
+	maxTriangles := 2*n - 5
+	if maxTriangles < 1 {
+		maxTriangles = 1
+	}
+	d.triangles = make([]int, 0, 3*maxTriangles)
+	d.halfedges = make([]int, 0, 3*maxTriangles)
+	d.addTriangle(i0, i1, i2, -1, -1, -1)
+
+	for _, i := range ids {
+		if i == i0 || i == i1 || i == i2 {
+			continue
+		}
+		p := points[i]
+		// a hull edge visible from the point, found from the hull point at about the same angle
+		key := d.hashKey(p)
+		start := 0
+		for j := 0; j < len(d.hullHash); j++ {
+			start = d.hullHash[(key+j)%len(d.hullHash)]
+			if start != -1 && start != d.hullNext[start] {
+				break
+			}
+		}
+		start = d.hullPrev[start]
+		e := start
+		for q := d.hullNext[e]; !orient(p, points[e], points[q]); q = d.hullNext[e] {
+			e = q
+			if e == start {
+				e = -1
+				break
+			}
+		}
+		if e == -1 {
+			// on the hull, within rounding
+			continue
+		}
+
+		t := d.addTriangle(e, i, d.hullNext[e], -1, -1, d.hullTri[e])
+		d.hullTri[i] = d.legalize(t + 2)
+		d.hullTri[e] = t
+		// the other hull edges visible from the point, forward then backward
+		next := d.hullNext[e]
+		for q := d.hullNext[next]; orient(p, points[next], points[q]); q = d.hullNext[next] {
+			t := d.addTriangle(next, i, q, d.hullTri[i], -1, d.hullTri[next])
+			d.hullTri[i] = d.legalize(t + 2)
+			d.hullNext[next] = next
+			next = q
+		}
+		if e == start {
+			for q := d.hullPrev[e]; orient(p, points[q], points[e]); q = d.hullPrev[e] {
+				t := d.addTriangle(q, i, e, -1, d.hullTri[e], d.hullTri[q])
+				d.legalize(t + 2)
+				d.hullTri[q] = t
+				d.hullNext[e] = e
+				e = q
+			}
+		}
+		d.hullStart, d.hullPrev[i] = e, e
+		d.hullNext[e], d.hullPrev[next] = i, i
+		d.hullNext[i] = next
+		d.hullHash[d.hashKey(p)] = i
+		d.hullHash[d.hashKey(points[e])] = e
+	}
+	return d
+}
+
+// hashKey buckets a point by its angle from the center, without trigonometry
+func (d *delaunay) hashKey(p vec) int {
+	dx, dy := p[0]-d.center[0], p[1]-d.center[1]
+	angle := dx / (math.Abs(dx) + math.Abs(dy))
+	if dy > 0 {
+		angle = 3 - angle
+	} else {
+		angle = 1 + angle
+	}
+	return int(math.Floor(angle/4*float64(len(d.hullHash)))) % len(d.hullHash)
+}
+
+func (d *delaunay) addTriangle(i0, i1, i2, a, b, c int) int {
+	t := len(d.triangles)
+	d.triangles = append(d.triangles, i0, i1, i2)
+	d.halfedges = append(d.halfedges, -1, -1, -1)
+	d.link(t, a)
+	d.link(t+1, b)
+	d.link(t+2, c)
+	return t
+}
+
+func (d *delaunay) link(a, b int) {
+	d.halfedges[a] = b
+	if b != -1 {
+		d.halfedges[b] = a
+	}
+}
+
+// legalize flips halfedge a and the edges around it until they're all Delaunay, returning the halfedge
+// which replaced the one before a
+func (d *delaunay) legalize(a int) int {
+	d.edges = d.edges[:0]
+	ar := 0
+	for {
+		b := d.halfedges[a]
+		a0 := a - a%3
+		ar = a0 + (a+2)%3
+		if b == -1 {
+			if len(d.edges) == 0 {
+				break
+			}
+			a, d.edges = d.edges[len(d.edges)-1], d.edges[:len(d.edges)-1]
+			continue
+		}
+		b0 := b - b%3
+		al := a0 + (a+1)%3
+		bl := b0 + (b+2)%3
+		p0, pr, pl, p1 := d.triangles[ar], d.triangles[a], d.triangles[al], d.triangles[bl]
+		if inCircle(d.points[p0], d.points[pr], d.points[pl], d.points[p1]) {
+			d.triangles[a], d.triangles[b] = p1, p0
+			hbl := d.halfedges[bl]
+			if hbl == -1 {
+				// the flipped edge was on the hull
+				e := d.hullStart
+				for {
+					if d.hullTri[e] == bl {
+						d.hullTri[e] = a
+						break
+					}
+					e = d.hullPrev[e]
+					if e == d.hullStart {
+						break
+					}
+				}
+			}
+			d.link(a, hbl)
+			d.link(b, d.halfedges[ar])
+			d.link(ar, bl)
+			d.edges = append(d.edges, b0+(b+1)%3)
+			continue
+		}
+		if len(d.edges) == 0 {
+			break
+		}
+		a, d.edges = d.edges[len(d.edges)-1], d.edges[:len(d.edges)-1]
+	}
+	return ar
+}
+
+func (d *delaunay) length(e int) float64 {
+	p, q := d.points[d.triangles[e]], d.points[d.triangles[nextHalfedge(e)]]
+	return math.Hypot(q[0]-p[0], q[1]-p[1])
+}
+
+// chiShape removes the triangles on the outside of the triangulation, longest edge first, while the edge
+// is longer than the threshold and the point across it isn't on the boundary already, which keeps the
+// shape regular. It returns the boundary of the triangles left, counterclockwise.
+func (d *delaunay) chiShape(threshold float64) ring {
+	removed := make([]bool, len(d.triangles)/3)
+	boundary := make([]bool, len(d.points))
+	queue := &edgeQueue{}
+	for e, twin := range d.halfedges {
+		if twin == -1 {
+			boundary[d.triangles[e]] = true
+			heap.Push(queue, edgeItem{e, d.length(e)})
+		}
+	}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(edgeItem)
+		if item.length <= threshold {
+			break
+		}
+		e := item.halfedge
+		across := nextHalfedge(nextHalfedge(e))
+		if removed[e/3] || boundary[d.triangles[across]] {
+			continue
+		}
+		removed[e/3] = true
+		boundary[d.triangles[across]] = true
+		for _, f := range []int{nextHalfedge(e), across} {
+			twin := d.halfedges[f]
+			heap.Push(queue, edgeItem{twin, d.length(twin)})
+		}
+	}
+
+	next := map[int]int{}
+	start := -1
+	for e, twin := range d.halfedges {
+		if !removed[e/3] && (twin == -1 || removed[twin/3]) {
+			start = d.triangles[e]
+			next[start] = d.triangles[nextHalfedge(e)]
+		}
+	}
+	r := ring{d.points[start]}
+	for i := next[start]; i != start; i = next[i] {
+		r = append(r, d.points[i])
+	}
+	r = append(r, r[0])
+	if r.signedArea() < 0 {
+		r = r.reversed()
+	}
+	return r
+}
+
+type edgeItem struct {
+	halfedge int
+	length   float64
+}
+
+// edgeQueue is a heap of the halfedges with the longest first
+type edgeQueue []edgeItem
+
+func (q edgeQueue) Len() int            { return len(q) }
+func (q edgeQueue) Less(i, j int) bool  { return q[i].length > q[j].length }
+func (q edgeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *edgeQueue) Push(x interface{}) { *q = append(*q, x.(edgeItem)) }
+func (q *edgeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func squaredDistance(p, q vec) float64 {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	return dx*dx + dy*dy
+}
+
+// orient is true when p q r turn counterclockwise
+func orient(p, q, r vec) bool {
+	return (q[1]-p[1])*(r[0]-q[0])-(q[0]-p[0])*(r[1]-q[1]) < 0
+}
+
+func circumradius(a, b, c vec) float64 {
+	center := circumcenter(a, b, c)
+	r := squaredDistance(a, center)
+	if math.IsNaN(r) {
+		return math.Inf(1)
+	}
+	return r
+}
+
+func circumcenter(a, b, c vec) vec {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	ex, ey := c[0]-a[0], c[1]-a[1]
+	bl, cl := dx*dx+dy*dy, ex*ex+ey*ey
+	scale := 0.5 / (dx*ey - dy*ex)
+	return vec{a[0] + (ey*bl-dy*cl)*scale, a[1] + (dx*cl-ex*bl)*scale}
+}
+
+// inCircle is true when p is inside the circumcircle of a b c
+func inCircle(a, b, c, p vec) bool {
+	dx, dy := a[0]-p[0], a[1]-p[1]
+	ex, ey := b[0]-p[0], b[1]-p[1]
+	fx, fy := c[0]-p[0], c[1]-p[1]
+	ap, bp, cp := dx*dx+dy*dy, ex*ex+ey*ey, fx*fx+fy*fy
+	return dx*(ey*cp-bp*fy)-dy*(ex*cp-bp*fx)+ap*(ex*fy-ey*fx) < 0
+}
@@ -0,0 +1,175 @@
+package spatially
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// uShape returns points spread over a U 2km across and 2km high, with arms 500m wide
+func uShape() [][]float64 {
+	random := rand.New(rand.NewSource(1))
+	projection := newLocalProjection([]float64{-71.06, 42.35, -71.06, 42.35})
+	var points [][]float64
+	for len(points) < 500 {
+		x, y := 2000*random.Float64(), 2000*random.Float64()
+		if x < 500 || x > 1500 || y < 500 {
+			points = append(points, projection.inverse(vec{x - 1000, y - 1000}))
+		}
+	}
+	return points
+}
+
+func TestConvexHull(t *testing.T) {
+	points := [][]float64{{-71.06, 42.35}, {-71.05, 42.35}, {-71.055, 42.355}, {-71.05, 42.36, 10}, {-71.06, 42.36}, {-71.055, 42.35}, {-71.06, 42.35}}
+	hull, err := ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wkt, _ := GeometryToWKT(hull); wkt != "POLYGON((-71.06 42.35,-71.05 42.35,-71.05 42.36,-71.06 42.36,-71.06 42.35))" {
+		t.Error("Unexpected hull", wkt)
+	}
+
+	points = uShape()
+	hull, err = ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(hull); err != nil {
+		t.Error(err)
+	}
+	if !Within(geojson.NewMultiPointGeometry(points...), hull) {
+		t.Error("Expected the hull to contain the points")
+	}
+}
+
+func TestConcaveHull(t *testing.T) {
+	points := uShape()
+	convex, err := ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		options  *ConcaveHullOptions
+		min, max float64
+	}{
+		{&ConcaveHullOptions{LengthRatio: 1}, Area(convex), Area(convex)},
+		// the inside of the U is cut out, leaving about its 2.5km²
+		{&ConcaveHullOptions{MaxEdgeLength: 300}, 2e6, 2.6e6},
+		{&ConcaveHullOptions{LengthRatio: 0.2}, 2e6, 2.6e6},
+		// and then some of the U
+		{nil, 0.5e6, 2e6},
+	} {
+		hull, err := ConcaveHull(points, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if area := Area(hull); area < test.min-1 || area > test.max+1 {
+			t.Errorf("Expected an area between %f and %f m² for %+v, got %f", test.min, test.max, test.options, area)
+		}
+		if hull.Type != geojson.GeometryPolygon || len(hull.Polygon) != 1 {
+			t.Errorf("Expected a polygon without holes, got %+v", hull)
+		}
+		if err := Validate(hull); err != nil {
+			t.Error(err)
+		}
+		if !Within(geojson.NewMultiPointGeometry(points...), hull) {
+			t.Errorf("Expected the hull for %+v to contain the points", test.options)
+		}
+	}
+}
+
+func TestHullErrors(t *testing.T) {
+	for _, points := range [][][]float64{
+		nil,
+		{{0, 0}, {1, 1}, {0, 0}},
+		{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
+		{{0, 0}, {1, 1}, {2}},
+		{{0, 0}, {1, 1}, {2, math.NaN()}},
+	} {
+		if _, err := ConvexHull(points); err == nil {
+			t.Error("Expected an error for the convex hull of", points)
+		}
+		if _, err := ConcaveHull(points, nil); err == nil {
+			t.Error("Expected an error for the concave hull of", points)
+		}
+	}
+	points := [][]float64{{0, 0}, {1, 0}, {0, 1}}
+	for _, options := range []*ConcaveHullOptions{{MaxEdgeLength: -1}, {LengthRatio: 1.5}, {LengthRatio: math.NaN()}} {
+		if _, err := ConcaveHull(points, options); err == nil {
+			t.Errorf("Expected an error for %+v", options)
+		}
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var points []vec
+	for i := 0; i < 300; i++ {
+		points = append(points, vec{random.Float64(), random.Float64()})
+	}
+	// points on a grid have many cocircular points
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			points = append(points, vec{2 + float64(x), float64(y)})
+		}
+	}
+	d := triangulate(points)
+	hull := 0
+	for e, twin := range d.halfedges {
+		if twin == -1 {
+			hull++
+		} else if d.halfedges[twin] != e || d.triangles[twin] != d.triangles[nextHalfedge(e)] {
+			t.Fatal("Expected halfedges to be linked both ways at", e)
+		}
+	}
+	if triangles := len(d.triangles) / 3; triangles != 2*len(points)-2-hull {
+		t.Errorf("Expected %d triangles, got %d", 2*len(points)-2-hull, triangles)
+	}
+	for i := 0; i < len(d.triangles); i += 3 {
+		a, b, c := d.points[d.triangles[i]], d.points[d.triangles[i+1]], d.points[d.triangles[i+2]]
+		center := circumcenter(a, b, c)
+		radius := squaredDistance(a, center)
+		for _, p := range d.points {
+			if squaredDistance(p, center) < radius*(1-1e-9) {
+				t.Fatalf("Expected no point in the circumcircle of %v %v %v, got %v", a, b, c, p)
+			}
+		}
+	}
+}
+
+func TestFeaturesHull(t *testing.T) {
+	features := NewFeatures()
+	for _, wkt := range []string{"POINT(-71.06 42.35)", "LINESTRING(-71.05 42.35,-71.05 42.36)", "POLYGON((-71.06 42.36,-71.055 42.365,-71.056 42.36,-71.06 42.36))"} {
+		feature, err := NewFeatureFromWKT(wkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		features = append(features, feature)
+	}
+	hull, err := features.ConvexHull()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wkt, _ := GeometryToWKT(hull); wkt != "POLYGON((-71.06 42.35,-71.05 42.35,-71.05 42.36,-71.055 42.365,-71.06 42.36,-71.06 42.35))" {
+		t.Error("Unexpected hull", wkt)
+	}
+	if _, err := features.ConcaveHull(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkConcaveHull(b *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	points := make([][]float64, 100000)
+	for i := range points {
+		points[i] = []float64{-71.1 + 0.1*random.Float64(), 42.3 + 0.1*random.Float64()}
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := ConcaveHull(points, &ConcaveHullOptions{LengthRatio: 0.1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
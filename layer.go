@@ -2,6 +2,7 @@ package spatially
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -28,13 +29,16 @@ func NewLayer() *Layer {
 
 // Get - Given a layer id, retrieves the layer and updates receiver
 func (l *Layer) Get(db API, id string) (err error) {
-	request, err := http.NewRequest("GET", SpatiallyAPI+"/spatialdb/layer/"+id, nil)
+	return l.GetContext(context.Background(), db, id)
+}
+
+// GetContext is Get with a context used to cancel the request or set its deadline
+func (l *Layer) GetContext(ctx context.Context, db API, id string) (err error) {
+	request, err := clientOf(db).NewRequestContext(ctx, "GET", "/spatialdb/layer/"+id, nil)
 	if err != nil {
 		return errors.Wrap(err, "get layer prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "get layer http get")
 	}
@@ -43,10 +47,13 @@ func (l *Layer) Get(db API, id string) (err error) {
 	if err != nil {
 		return errors.Wrap(err, "get layer read response body")
 	}
-	if len(responseBody) == 0 {
-		return fmt.Errorf("Layer not found, ID: %v", id)
-	} else if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+	if resp.StatusCode != 200 {
+		return newAPIError(request, resp, responseBody)
+	} else if len(responseBody) == 0 {
+		notFound := newAPIError(request, resp, responseBody)
+		notFound.StatusCode = http.StatusNotFound
+		notFound.Message = fmt.Sprintf("Layer not found, ID: %v", id)
+		return notFound
 	}
 	if err = json.Unmarshal(responseBody, l); err != nil {
 		return errors.Wrap(err, "get layer parse response body json")
@@ -64,6 +71,11 @@ type createLayerRequest struct {
 
 // Create - Given a layer name, creates the layer and updates receiver
 func (l *Layer) Create(db API, name string) (err error) {
+	return l.CreateContext(context.Background(), db, name)
+}
+
+// CreateContext is Create with a context used to cancel the request or set its deadline
+func (l *Layer) CreateContext(ctx context.Context, db API, name string) (err error) {
 	requestBody := createLayerRequest{
 		Name: name,
 	}
@@ -72,13 +84,11 @@ func (l *Layer) Create(db API, name string) (err error) {
 		return errors.Wrap(err, "spatialdb create layer json marshal request body")
 	}
 	body := bytes.NewReader(j)
-	request, err := http.NewRequest("POST", SpatiallyAPI+"/spatialdb/layer", body)
+	request, err := clientOf(db).NewRequestContext(ctx, "POST", "/spatialdb/layer", body)
 	if err != nil {
 		return errors.Wrap(err, "spatialdb create layer prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "spatialdb create layer http post")
 	}
@@ -88,7 +98,7 @@ func (l *Layer) Create(db API, name string) (err error) {
 		return errors.Wrap(err, "spatialdb create layer read response body")
 	}
 	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+		return newAPIError(request, resp, responseBody)
 	}
 	if err = json.Unmarshal(responseBody, l); err != nil {
 		return errors.Wrap(err, "spatialdb create layer parse response body json")
@@ -102,19 +112,26 @@ func (l *Layer) Create(db API, name string) (err error) {
 
 // Delete - Given a layer id, deletes the layer
 func (l *Layer) Delete(db API, id string) (err error) {
-	request, err := http.NewRequest("DELETE", SpatiallyAPI+"/spatialdb/layer/"+id, nil)
+	return l.DeleteContext(context.Background(), db, id)
+}
+
+// DeleteContext is Delete with a context used to cancel the request or set its deadline
+func (l *Layer) DeleteContext(ctx context.Context, db API, id string) (err error) {
+	request, err := clientOf(db).NewRequestContext(ctx, "DELETE", "/spatialdb/layer/"+id, nil)
 	if err != nil {
 		return errors.Wrap(err, "spatialdb delete layer prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "spatialdb delete layer http delete")
 	}
 	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "spatialdb delete layer read response body")
+	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("There was an unexpected error deleting layer ID: %v", id)
+		return newAPIError(request, resp, responseBody)
 	}
 	return
 }
@@ -127,13 +144,16 @@ func NewLayers() Layers {
 // Get - Retrieves all layers that belong to this user and updates the slice receiver.
 // It does not retrieve layer features
 func (l *Layers) Get(db API) (err error) {
-	request, err := http.NewRequest("GET", SpatiallyAPI+"/spatialdb/layers", nil)
+	return l.GetContext(context.Background(), db)
+}
+
+// GetContext is Get with a context used to cancel the request or set its deadline
+func (l *Layers) GetContext(ctx context.Context, db API) (err error) {
+	request, err := clientOf(db).NewRequestContext(ctx, "GET", "/spatialdb/layers", nil)
 	if err != nil {
 		return errors.Wrap(err, "get layers prepare http request")
 	}
-	db.PrepareRequest(request)
-	requestClient := &http.Client{}
-	resp, err := requestClient.Do(request)
+	resp, err := send(db, request)
 	if err != nil {
 		return errors.Wrap(err, "get layers http get")
 	}
@@ -143,7 +163,7 @@ func (l *Layers) Get(db API) (err error) {
 		return errors.Wrap(err, "get layers read response body")
 	}
 	if resp.StatusCode != 200 {
-		return db.Error(responseBody)
+		return newAPIError(request, resp, responseBody)
 	}
 	if err = json.Unmarshal(responseBody, l); err != nil {
 		return errors.Wrap(err, "get layers parse response body json")
@@ -0,0 +1,434 @@
+package spatially
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+// meanEarthRadius is the mean radius of the WGS84 ellipsoid in meters
+const meanEarthRadius = 6371008.8
+
+// Area returns the area of the polygons of g in square meters, measured on the WGS84 ellipsoid. Holes are
+// subtracted and other geometries have no area. As GeoJSON prescribes, polygon edges are straight lines in
+// longitude & latitude.
+func Area(g *geojson.Geometry) float64 {
+	_, _, polygons := flatten(g)
+	area := 0.0
+	for _, polygon := range polygons {
+		area += polygonArea(polygon)
+	}
+	return area
+}
+
+func polygonArea(polygon [][][]float64) float64 {
+	area := 0.0
+	for i, ring := range polygon {
+		if i == 0 {
+			area += math.Abs(ringArea(ring))
+		} else {
+			area -= math.Abs(ringArea(ring))
+		}
+	}
+	return math.Max(area, 0)
+}
+
+// ringArea returns the signed area of a ring on the ellipsoid, positive when counterclockwise. Each edge
+// contributes the area between it and the equator, integrated with Simpson's rule over the authalic
+// function of its latitudes.
+func ringArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		p, q := ring[i], ring[i+1]
+		lat1, lat2 := p[1]*math.Pi/180, q[1]*math.Pi/180
+		dLon := (q[0] - p[0]) * math.Pi / 180
+		area -= dLon * (authalic(lat1) + 4*authalic((lat1+lat2)/2) + authalic(lat2)) / 6
+	}
+	return area
+}
+
+// authalic returns the area of the ellipsoid between the equator and a latitude, per radian of longitude
+func authalic(lat float64) float64 {
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	e := math.Sqrt(e2)
+	sinLat := math.Sin(lat)
+	q := (1 - e2) * (sinLat/(1-e2*sinLat*sinLat) - math.Log((1-e*sinLat)/(1+e*sinLat))/(2*e))
+	return wgs84SemiMajorAxis * wgs84SemiMajorAxis / 2 * q
+}
+
+// Length returns the length of the linestrings of g in meters, along geodesics of the WGS84 ellipsoid.
+// Polygons have no length, see Perimeter.
+func Length(g *geojson.Geometry) float64 {
+	_, lines, _ := flatten(g)
+	length := 0.0
+	for _, line := range lines {
+		length += lineLength(line)
+	}
+	return length
+}
+
+// Perimeter returns the length of the rings of the polygons of g in meters, holes included, along geodesics
+// of the WGS84 ellipsoid
+func Perimeter(g *geojson.Geometry) float64 {
+	_, _, polygons := flatten(g)
+	perimeter := 0.0
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			perimeter += lineLength(ring)
+		}
+	}
+	return perimeter
+}
+
+func lineLength(line [][]float64) float64 {
+	length := 0.0
+	for i := 0; i+1 < len(line); i++ {
+		length += geodesicDistance(line[i], line[i+1])
+	}
+	return length
+}
+
+// geodesicDistance returns the distance in meters between two longitude/latitude points using Vincenty's
+// inverse formula. Nearly antipodal points, where it doesn't converge, fall back to the haversine distance.
+func geodesicDistance(p, q []float64) float64 {
+	a := wgs84SemiMajorAxis
+	f := wgs84Flattening
+	b := a * (1 - f)
+	l := (q[0] - p[0]) * math.Pi / 180
+	u1 := math.Atan((1 - f) * math.Tan(p[1]*math.Pi/180))
+	u2 := math.Atan((1 - f) * math.Tan(q[1]*math.Pi/180))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+	lambda := l
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		c := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+		previous := lambda
+		lambda = l + (1-c)*f*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) < 1e-12 {
+			uSq := cos2Alpha * (a*a - b*b) / (b * b)
+			bigA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+			bigB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+			deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return b * bigA * (sigma - deltaSigma)
+		}
+	}
+	return haversineDistance(p, q)
+}
+
+// geodesicDestination returns the point at a distance in meters from p along the geodesic leaving it at a
+// bearing in degrees clockwise from north, using Vincenty's direct formula
+func geodesicDestination(p []float64, bearing, meters float64) []float64 {
+	a := wgs84SemiMajorAxis
+	f := wgs84Flattening
+	b := a * (1 - f)
+	sinAlpha1, cosAlpha1 := math.Sincos(bearing * math.Pi / 180)
+	tanU1 := (1 - f) * math.Tan(p[1]*math.Pi/180)
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cos2Alpha := 1 - sinAlpha*sinAlpha
+	uSq := cos2Alpha * (a*a - b*b) / (b * b)
+	bigA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	bigB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	sigma := meters / (b * bigA)
+	var sinSigma, cosSigma, cos2SigmaM float64
+	for i := 0; i < 200; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		previous := sigma
+		sigma = meters/(b*bigA) + deltaSigma
+		if math.Abs(sigma-previous) < 1e-12 {
+			break
+		}
+	}
+	sinSigma, cosSigma = math.Sincos(sigma)
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	lat := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-f)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	c := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+	l := lambda - (1-c)*f*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+	return []float64{p[0] + l*180/math.Pi, lat * 180 / math.Pi}
+}
+
+// haversineDistance returns the great circle distance in meters between two longitude/latitude points
+func haversineDistance(p, q []float64) float64 {
+	lat1, lat2 := p[1]*math.Pi/180, q[1]*math.Pi/180
+	sinDLat := math.Sin((lat2 - lat1) / 2)
+	sinDLon := math.Sin((q[0] - p[0]) * math.Pi / 180 / 2)
+	h := sinDLat*sinDLat + math.Cos(lat1)*math.Cos(lat2)*sinDLon*sinDLon
+	return 2 * meanEarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Centroid returns the center of mass of g as a [longitude, latitude] point. Like PostGIS' ST_Centroid
+// only the parts with the highest dimension count: polygons weighted by their area, or else linestrings
+// weighted by their length, or else points. The centroid of a concave shape can be outside of it, see
+// RepresentativePoint.
+func Centroid(g *geojson.Geometry) ([]float64, error) {
+	points, lines, polygons := flatten(g)
+	if c := polygonsCentroid(polygons); c != nil {
+		return c, nil
+	}
+	for _, polygon := range polygons {
+		lines = append(lines, polygon...)
+	}
+	if c := linesCentroid(lines); c != nil {
+		return c, nil
+	}
+	for _, line := range lines {
+		points = append(points, line...)
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("empty geometry has no centroid")
+	}
+	x, y := 0.0, 0.0
+	for _, p := range points {
+		x += p[0]
+		y += p[1]
+	}
+	return []float64{x / float64(len(points)), y / float64(len(points))}, nil
+}
+
+// polygonsCentroid returns the area weighted centroid of polygons, nil when they have no area. The
+// centroid is computed in longitude & latitude, which only scales the ellipsoid's coordinates locally.
+func polygonsCentroid(polygons [][][][]float64) []float64 {
+	if len(polygons) == 0 || len(polygons[0]) == 0 || len(polygons[0][0]) == 0 {
+		return nil
+	}
+	// coordinates are taken relative to the first vertex to keep precision
+	origin := polygons[0][0][0]
+	area, x, y := 0.0, 0.0, 0.0
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			ringArea, ringX, ringY := 0.0, 0.0, 0.0
+			for j := 0; j+1 < len(ring); j++ {
+				x0, y0 := ring[j][0]-origin[0], ring[j][1]-origin[1]
+				x1, y1 := ring[j+1][0]-origin[0], ring[j+1][1]-origin[1]
+				cross := x0*y1 - x1*y0
+				ringArea += cross / 2
+				ringX += (x0 + x1) * cross / 6
+				ringY += (y0 + y1) * cross / 6
+			}
+			// outer rings add to the polygon and holes subtract, whatever their orientation
+			sign := 1.0
+			if (ringArea < 0) != (i > 0) {
+				sign = -1
+			}
+			area += sign * ringArea
+			x += sign * ringX
+			y += sign * ringY
+		}
+	}
+	if area <= 0 {
+		return nil
+	}
+	return []float64{origin[0] + x/area, origin[1] + y/area}
+}
+
+// linesCentroid returns the centroid of linestrings with each segment weighted by its length, nil when
+// they have no length
+func linesCentroid(lines [][][]float64) []float64 {
+	length, x, y := 0.0, 0.0, 0.0
+	for _, line := range lines {
+		for i := 0; i+1 < len(line); i++ {
+			d := geodesicDistance(line[i], line[i+1])
+			length += d
+			x += d * (line[i][0] + line[i+1][0]) / 2
+			y += d * (line[i][1] + line[i+1][1]) / 2
+		}
+	}
+	if length == 0 {
+		return nil
+	}
+	return []float64{x / length, y / length}
+}
+
+// RepresentativePoint returns a [longitude, latitude] point on g, inside its polygons when it has any.
+// Unlike the centroid it can't fall in the hole of a donut or the notch of a U shape. Polygons are
+// crossed with a line of latitude through the middle of their bounds and the point is the middle of the
+// widest stretch inside them. Otherwise it's the vertex of g closest to its centroid.
+func RepresentativePoint(g *geojson.Geometry) ([]float64, error) {
+	points, lines, polygons := flatten(g)
+	var best []float64
+	widest := -1.0
+	for _, polygon := range polygons {
+		if p, width := widestInterior(polygon); width > widest {
+			best, widest = p, width
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+	centroid, err := Centroid(g)
+	if err != nil {
+		return nil, err
+	}
+	var candidates [][]float64
+	for _, line := range lines {
+		if len(line) > 2 {
+			candidates = append(candidates, line[1:len(line)-1]...)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, line := range lines {
+			candidates = append(candidates, line...)
+		}
+		candidates = append(candidates, points...)
+	}
+	closest := math.Inf(1)
+	for _, p := range candidates {
+		if d := math.Hypot(p[0]-centroid[0], p[1]-centroid[1]); d < closest {
+			best, closest = []float64{p[0], p[1]}, d
+		}
+	}
+	return best, nil
+}
+
+// widestInterior crosses a polygon with a line of latitude between the vertices closest to the middle
+// of its bounds and returns the middle of the widest interval inside it, with the interval's width
+func widestInterior(polygon [][][]float64) ([]float64, float64) {
+	if len(polygon) == 0 || len(polygon[0]) < 4 {
+		return nil, -1
+	}
+	south, north := math.Inf(1), math.Inf(-1)
+	for _, p := range polygon[0] {
+		south, north = math.Min(south, p[1]), math.Max(north, p[1])
+	}
+	middle := (south + north) / 2
+	below, above := south, north
+	for _, ring := range polygon {
+		for _, p := range ring {
+			if p[1] <= middle && p[1] > below {
+				below = p[1]
+			}
+			if p[1] > middle && p[1] < above {
+				above = p[1]
+			}
+		}
+	}
+	lat := (below + above) / 2
+	var crossings []float64
+	for _, ring := range polygon {
+		for i := 0; i+1 < len(ring); i++ {
+			p, q := ring[i], ring[i+1]
+			if (p[1] > lat) != (q[1] > lat) {
+				crossings = append(crossings, p[0]+(lat-p[1])*(q[0]-p[0])/(q[1]-p[1]))
+			}
+		}
+	}
+	sort.Float64s(crossings)
+	var best []float64
+	widest := -1.0
+	for i := 0; i+1 < len(crossings); i += 2 {
+		if width := crossings[i+1] - crossings[i]; width > widest {
+			best, widest = []float64{(crossings[i] + crossings[i+1]) / 2, lat}, width
+		}
+	}
+	return best, widest
+}
+
+// BBox returns the [west, south, east, north] bounds of g, nil when g is empty
+func BBox(g *geojson.Geometry) []float64 {
+	var bbox []float64
+	extend := func(p []float64) {
+		if bbox == nil {
+			bbox = []float64{p[0], p[1], p[0], p[1]}
+			return
+		}
+		bbox[0], bbox[1] = math.Min(bbox[0], p[0]), math.Min(bbox[1], p[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], p[0]), math.Max(bbox[3], p[1])
+	}
+	points, lines, polygons := flatten(g)
+	for _, p := range points {
+		extend(p)
+	}
+	for _, line := range lines {
+		for _, p := range line {
+			extend(p)
+		}
+	}
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			for _, p := range ring {
+				extend(p)
+			}
+		}
+	}
+	return bbox
+}
+
+// Area returns the area of the feature's polygons in square meters, see Area
+func (f *Feature) Area() float64 {
+	return Area(f.Geometry)
+}
+
+// Length returns the length of the feature's linestrings in meters, see Length
+func (f *Feature) Length() float64 {
+	return Length(f.Geometry)
+}
+
+// Perimeter returns the length of the rings of the feature's polygons in meters, see Perimeter
+func (f *Feature) Perimeter() float64 {
+	return Perimeter(f.Geometry)
+}
+
+// Centroid returns the center of mass of the feature, see Centroid
+func (f *Feature) Centroid() ([]float64, error) {
+	return Centroid(f.Geometry)
+}
+
+// RepresentativePoint returns a point on the feature, see RepresentativePoint
+func (f *Feature) RepresentativePoint() ([]float64, error) {
+	return RepresentativePoint(f.Geometry)
+}
+
+// BBox returns the [west, south, east, north] bounds of the feature, see BBox
+func (f *Feature) BBox() []float64 {
+	return BBox(f.Geometry)
+}
+
+// GeoJSON returns the ATA as a geojson feature collection, whose geometries can be measured
+func (a *ATA) GeoJSON() (*geojson.FeatureCollection, error) {
+	j, err := json.Marshal(a.FeatureCollection)
+	if err != nil {
+		return nil, errors.Wrap(err, "ata json marshal")
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(j)
+	if err != nil {
+		return nil, errors.Wrap(err, "ata geojson unmarshal")
+	}
+	return fc, nil
+}
+
+// Area returns the area of the ATA's polygons in square meters, see Area
+func (a *ATA) Area() (float64, error) {
+	fc, err := a.GeoJSON()
+	if err != nil {
+		return 0, err
+	}
+	area := 0.0
+	for _, feature := range fc.Features {
+		area += Area(feature.Geometry)
+	}
+	return area, nil
+}
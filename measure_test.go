@@ -0,0 +1,159 @@
+package spatially
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Spatially/go-geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func mustWKT(t *testing.T, wkt string) *geojson.Geometry {
+	g, err := WKTToGeometry(wkt)
+	if err != nil {
+		t.Fatal(wkt, err)
+	}
+	return g
+}
+
+func TestArea(t *testing.T) {
+	for _, test := range []struct {
+		wkt       string
+		area      float64
+		tolerance float64
+	}{
+		// a one degree cell at the equator, exactly from the authalic latitude
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", 12308463894, 1e-6},
+		// clockwise and in the southern hemisphere
+		{"POLYGON((0 0,0 -1,1 -1,1 0,0 0))", 12308463894, 1e-6},
+		// Wyoming, 253,335 km² according to the census bureau
+		{"POLYGON((-111.05 41,-104.05 41,-104.05 45,-111.05 45,-111.05 41))", 253335e6, 2e-3},
+		// holes are subtracted
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0),(0 0,0 1,1 1,1 0,0 0))", 0, 1e-9},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((0 -1,1 -1,1 0,0 0,0 -1)))", 2 * 12308463894, 1e-6},
+		{"GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(0 0,1 1),POLYGON((0 0,1 0,1 1,0 1,0 0)))", 12308463894, 1e-6},
+		{"LINESTRING(0 0,1 1)", 0, 0},
+	} {
+		area := Area(mustWKT(t, test.wkt))
+		if math.Abs(area-test.area) > test.tolerance*math.Max(test.area, 1) {
+			t.Errorf("Expected an area of %f m² for %s, got %f", test.area, test.wkt, area)
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	for _, test := range []struct {
+		wkt    string
+		length float64
+	}{
+		// Vincenty's reference geodesic from Flinders Peak to Buninyong
+		{"LINESTRING(144.42486788972222 -37.95103341666667,143.92649552305556 -37.65282113888889)", 54972.271},
+		{"LINESTRING(0 0,1 0)", 111319.491},
+		{"LINESTRING(0 0,0 1)", 110574.389},
+		{"MULTILINESTRING((0 0,1 0),(0 0,0 1))", 111319.491 + 110574.389},
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", 0},
+	} {
+		length := Length(mustWKT(t, test.wkt))
+		if math.Abs(length-test.length) > 1e-3 {
+			t.Errorf("Expected a length of %f m for %s, got %f", test.length, test.wkt, length)
+		}
+	}
+	// nearly antipodal points fall back to the great circle distance
+	if d := geodesicDistance([]float64{0, 0}, []float64{179.7, 0}); math.IsNaN(d) || math.Abs(d-20003931) > 30000 {
+		t.Error("Unexpected distance between nearly antipodal points", d)
+	}
+}
+
+func TestPerimeter(t *testing.T) {
+	polygon := mustWKT(t, "POLYGON((0 0,1 0,1 1,0 1,0 0),(0.2 0.2,0.2 0.4,0.4 0.4,0.4 0.2,0.2 0.2))")
+	expected := Length(mustWKT(t, "MULTILINESTRING((0 0,1 0,1 1,0 1,0 0),(0.2 0.2,0.2 0.4,0.4 0.4,0.4 0.2,0.2 0.2))"))
+	if perimeter := Perimeter(polygon); math.Abs(perimeter-expected) > 1e-6 || perimeter < 4*110000 {
+		t.Errorf("Expected a perimeter of %f m, got %f", expected, perimeter)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	for _, test := range []struct {
+		wkt      string
+		centroid []float64
+	}{
+		{"POINT(1 2)", []float64{1, 2}},
+		{"MULTIPOINT(0 0,2 0,4 3)", []float64{2, 1}},
+		{"LINESTRING(0 0,0 2)", []float64{0, 1}},
+		{"POLYGON((0 0,2 0,2 2,0 2,0 0))", []float64{1, 1}},
+		{"POLYGON((-71 42,-70 42,-70 43,-71 43,-71 42))", []float64{-70.5, 42.5}},
+		// a hole in the right half moves the centroid left
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(2 0,4 0,4 4,2 4,2 0))", []float64{1, 2}},
+		// the U shape's centroid is in its notch
+		{"POLYGON((0 0,3 0,3 3,2 3,2 1,1 1,1 3,0 3,0 0))", []float64{1.5, 9.5 / 7}},
+		// only the polygon counts
+		{"GEOMETRYCOLLECTION(POINT(10 10),POLYGON((0 0,2 0,2 2,0 2,0 0)))", []float64{1, 1}},
+	} {
+		c, err := Centroid(mustWKT(t, test.wkt))
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		if math.Abs(c[0]-test.centroid[0]) > 1e-6 || math.Abs(c[1]-test.centroid[1]) > 1e-6 {
+			t.Errorf("Expected centroid %v for %s, got %v", test.centroid, test.wkt, c)
+		}
+	}
+	if _, err := Centroid(mustWKT(t, "POLYGON EMPTY")); err == nil {
+		t.Error("Expected an error for an empty geometry")
+	}
+}
+
+func TestRepresentativePoint(t *testing.T) {
+	for _, test := range []struct {
+		wkt   string
+		point []float64
+	}{
+		{"POLYGON((0 0,3 0,3 3,2 3,2 1,1 1,1 3,0 3,0 0))", []float64{0.5, 2}},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(1 1,3 1,3 3,1 3,1 1))", []float64{0.5, 2}},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((5 0,8 0,8 1,5 1,5 0)))", []float64{6.5, 0.5}},
+		{"LINESTRING(0 0,1 5,2 1,4 0)", []float64{2, 1}},
+		{"MULTIPOINT(0 0,1 1,5 5)", []float64{1, 1}},
+	} {
+		p, err := RepresentativePoint(mustWKT(t, test.wkt))
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		if math.Abs(p[0]-test.point[0]) > 1e-9 || math.Abs(p[1]-test.point[1]) > 1e-9 {
+			t.Errorf("Expected representative point %v for %s, got %v", test.point, test.wkt, p)
+		}
+	}
+}
+
+func TestBBox(t *testing.T) {
+	bbox := BBox(mustWKT(t, "GEOMETRYCOLLECTION(POINT(-3 7),POLYGON((0 0,2 0,2 2,0 2,0 0)),LINESTRING(1 1,5 -1))"))
+	if len(bbox) != 4 || bbox[0] != -3 || bbox[1] != -1 || bbox[2] != 5 || bbox[3] != 7 {
+		t.Error("Unexpected bounds", bbox)
+	}
+	if bbox := BBox(mustWKT(t, "POINT EMPTY")); bbox != nil {
+		t.Error("Expected no bounds for an empty geometry, got", bbox)
+	}
+}
+
+func TestATAArea(t *testing.T) {
+	var fc geometry.FeatureCollection
+	j := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]},"properties":{}},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[0.5,0.5]},"properties":{}}]}`
+	if err := json.Unmarshal([]byte(j), &fc); err != nil {
+		t.Fatal(err)
+	}
+	ata := &ATA{&fc}
+	area, err := ata.Area()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(area-12308463894) > 1e4 {
+		t.Error("Unexpected ATA area", area)
+	}
+	feature := &Feature{Feature: geojson.NewFeature(mustWKT(t, "LINESTRING(0 0,1 0)"))}
+	if math.Abs(feature.Length()-111319.491) > 1e-3 || feature.Area() != 0 {
+		t.Error("Unexpected feature measures", feature.Length(), feature.Area())
+	}
+}
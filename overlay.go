@@ -0,0 +1,537 @@
+package spatially
+
+import (
+	"math"
+	"sort"
+)
+
+// overlayTolerance is the distance, in the planar units of the overlay, under which a vertex is on an edge
+const overlayTolerance = 1e-6
+
+// vec is a planar coordinate of the overlay, comparable so it can key the graph of edges
+type vec [2]float64
+
+func (v vec) sub(w vec) vec {
+	return vec{v[0] - w[0], v[1] - w[1]}
+}
+
+func cross(v, w vec) float64 {
+	return v[0]*w[1] - v[1]*w[0]
+}
+
+// ring is a closed planar ring, its first vertex repeated last
+type ring []vec
+
+// signedArea is positive for counterclockwise rings
+func (r ring) signedArea() float64 {
+	area := 0.0
+	for i := 0; i+1 < len(r); i++ {
+		area += cross(r[i], r[i+1])
+	}
+	return area / 2
+}
+
+func (r ring) reversed() ring {
+	reversed := make(ring, len(r))
+	for i, v := range r {
+		reversed[len(r)-1-i] = v
+	}
+	return reversed
+}
+
+func (r ring) contains(v vec) bool {
+	inside := false
+	for i := 0; i+1 < len(r); i++ {
+		p, q := r[i], r[i+1]
+		if (p[1] > v[1]) != (q[1] > v[1]) && v[0] < p[0]+(v[1]-p[1])*(q[0]-p[0])/(q[1]-p[1]) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// planarPolygon is a shell with its holes
+type planarPolygon []ring
+
+func (p planarPolygon) bbox() [4]float64 {
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, v := range p[0] {
+		bbox[0], bbox[1] = math.Min(bbox[0], v[0]), math.Min(bbox[1], v[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], v[0]), math.Max(bbox[3], v[1])
+	}
+	return bbox
+}
+
+// locate finds where v is relative to the polygon, within the overlay tolerance of its rings being on them
+func (p planarPolygon) locate(v vec) location {
+	for _, r := range p {
+		for i := 0; i+1 < len(r); i++ {
+			if distanceToEdge(v, r[i], r[i+1]) <= overlayTolerance {
+				return boundary
+			}
+		}
+	}
+	if !p[0].contains(v) {
+		return exterior
+	}
+	for _, hole := range p[1:] {
+		if hole.contains(v) {
+			return exterior
+		}
+	}
+	return interior
+}
+
+// bandedPolygon locates points in a polygon looking only at the edges in the horizontal band of the point,
+// which makes locating points in polygons of thousands of vertices cheap
+type bandedPolygon struct {
+	south, height float64
+	bands         [][][2]vec
+}
+
+func newBandedPolygon(p planarPolygon) *bandedPolygon {
+	bbox := p.bbox()
+	edges := 0
+	for _, r := range p {
+		edges += len(r)
+	}
+	count := edges/4 + 1
+	b := &bandedPolygon{south: bbox[1], height: (bbox[3] - bbox[1]) / float64(count), bands: make([][][2]vec, count)}
+	if b.height == 0 {
+		b.height = 1
+	}
+	for _, r := range p {
+		for i := 0; i+1 < len(r); i++ {
+			first, last := b.band(math.Min(r[i][1], r[i+1][1])-overlayTolerance), b.band(math.Max(r[i][1], r[i+1][1])+overlayTolerance)
+			for j := first; j <= last; j++ {
+				b.bands[j] = append(b.bands[j], [2]vec{r[i], r[i+1]})
+			}
+		}
+	}
+	return b
+}
+
+func (b *bandedPolygon) band(y float64) int {
+	return int(math.Max(0, math.Min(float64(len(b.bands)-1), math.Floor((y-b.south)/b.height))))
+}
+
+// locate finds where v is relative to the polygon, counting the crossings of a ray cast from v with all
+// of its rings, which works for shells with holes inside of them
+func (b *bandedPolygon) locate(v vec) location {
+	inside := false
+	for _, e := range b.bands[b.band(v[1])] {
+		p, q := e[0], e[1]
+		if v[0] < math.Min(p[0], q[0])-overlayTolerance || v[0] > math.Max(p[0], q[0])+overlayTolerance {
+			// the edge is beside v, at most its ray crosses it
+		} else if distanceToEdge(v, p, q) <= overlayTolerance {
+			return boundary
+		}
+		if (p[1] > v[1]) != (q[1] > v[1]) && v[0] < p[0]+(v[1]-p[1])*(q[0]-p[0])/(q[1]-p[1]) {
+			inside = !inside
+		}
+	}
+	if inside {
+		return interior
+	}
+	return exterior
+}
+
+// oriented returns the polygon with a counterclockwise shell and clockwise holes, the orientation of
+// the GeoJSON right hand rule which puts the interior on the left of every edge
+func (p planarPolygon) oriented() planarPolygon {
+	oriented := make(planarPolygon, len(p))
+	for i, r := range p {
+		if (r.signedArea() < 0) == (i == 0) {
+			r = r.reversed()
+		}
+		oriented[i] = r
+	}
+	return oriented
+}
+
+// edgeParameter returns the parameter of the point of the edge p q closest to v, between 0 and 1
+func edgeParameter(v, p, q vec) float64 {
+	d := q.sub(p)
+	l2 := d[0]*d[0] + d[1]*d[1]
+	if l2 == 0 {
+		return 0
+	}
+	w := v.sub(p)
+	return math.Max(0, math.Min(1, (w[0]*d[0]+w[1]*d[1])/l2))
+}
+
+func distanceToEdge(v, p, q vec) float64 {
+	t := edgeParameter(v, p, q)
+	dx, dy := p[0]+t*(q[0]-p[0])-v[0], p[1]+t*(q[1]-p[1])-v[1]
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+// gridIndex finds the items whose bounds share a cell of a regular grid with some bounds
+type gridIndex struct {
+	size  float64
+	cells map[[2]int][]int
+}
+
+func newGridIndex(size float64) *gridIndex {
+	return &gridIndex{size: size, cells: map[[2]int][]int{}}
+}
+
+func (g *gridIndex) span(bbox [4]float64) (x0, y0, x1, y1 int) {
+	return int(math.Floor(bbox[0] / g.size)), int(math.Floor(bbox[1] / g.size)),
+		int(math.Floor(bbox[2] / g.size)), int(math.Floor(bbox[3] / g.size))
+}
+
+func (g *gridIndex) insert(item int, bbox [4]float64) {
+	x0, y0, x1, y1 := g.span(bbox)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			g.cells[[2]int{x, y}] = append(g.cells[[2]int{x, y}], item)
+		}
+	}
+}
+
+// query calls fn with the items near bbox, possibly more than once
+func (g *gridIndex) query(bbox [4]float64, fn func(item int)) {
+	x0, y0, x1, y1 := g.span(bbox)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			for _, item := range g.cells[[2]int{x, y}] {
+				fn(item)
+			}
+		}
+	}
+}
+
+type overlayEdge struct {
+	p, q   vec
+	owner  int
+	splits []vec
+}
+
+func (e *overlayEdge) bbox() [4]float64 {
+	return [4]float64{math.Min(e.p[0], e.q[0]) - overlayTolerance, math.Min(e.p[1], e.q[1]) - overlayTolerance,
+		math.Max(e.p[0], e.q[0]) + overlayTolerance, math.Max(e.p[1], e.q[1]) + overlayTolerance}
+}
+
+// split records the nodes where two edges meet on both of them. Nodes are shared exactly, so the pieces of
+// both edges join in the graph.
+func (e *overlayEdge) split(f *overlayEdge) {
+	if a, b := e.bbox(), f.bbox(); a[0] > b[2] || b[0] > a[2] || a[1] > b[3] || b[1] > a[3] {
+		return
+	}
+	for _, pair := range [][2]*overlayEdge{{e, f}, {f, e}} {
+		for _, v := range []vec{pair[0].p, pair[0].q} {
+			if v != pair[1].p && v != pair[1].q && distanceToEdge(v, pair[1].p, pair[1].q) <= overlayTolerance {
+				pair[1].splits = append(pair[1].splits, v)
+			}
+		}
+	}
+	r, s := e.q.sub(e.p), f.q.sub(f.p)
+	d := cross(r, s)
+	if d == 0 {
+		return
+	}
+	w := f.p.sub(e.p)
+	t, u := cross(w, s)/d, cross(w, r)/d
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return
+	}
+	v := vec{e.p[0] + t*r[0], e.p[1] + t*r[1]}
+	for _, end := range []vec{e.p, e.q, f.p, f.q} {
+		if math.Hypot(v[0]-end[0], v[1]-end[1]) <= overlayTolerance {
+			return
+		}
+	}
+	e.splits = append(e.splits, v)
+	f.splits = append(f.splits, v)
+}
+
+// pieces returns the edge cut at its nodes, in order from p to q
+func (e *overlayEdge) pieces() [][2]vec {
+	sort.Slice(e.splits, func(i, j int) bool {
+		return edgeParameter(e.splits[i], e.p, e.q) < edgeParameter(e.splits[j], e.p, e.q)
+	})
+	var pieces [][2]vec
+	from := e.p
+	for _, v := range append(e.splits, e.q) {
+		if v != from {
+			pieces = append(pieces, [2]vec{from, v})
+			from = v
+		}
+	}
+	return pieces
+}
+
+// unionPolygons returns the union of planar polygons as polygons whose shells are counterclockwise and
+// holes clockwise
+func unionPolygons(polygons []planarPolygon) []planarPolygon {
+	return overlay([][]planarPolygon{polygons}, func(inside []bool) bool { return inside[0] })
+}
+
+// evenOddPolygons rebuilds a polygon whose rings may cross themselves and each other, with the even-odd
+// rule: a point is inside when a ray cast from it crosses the rings an odd number of times
+func evenOddPolygons(p planarPolygon) []planarPolygon {
+	return overlay([][]planarPolygon{{p}}, func(inside []bool) bool { return inside[0] })
+}
+
+// overlay combines groups of planar polygons into polygons whose shells are counterclockwise and holes
+// clockwise. The rings of all the polygons are cut where they meet. Just beside each piece, keep decides
+// whether the region is in the result from whether it's inside a polygon of each group, and the pieces
+// with the result on one side only are joined into rings, directed to have the result on their left.
+// Pieces shared by several rings are considered once, so polygons sharing edges are handled like others.
+// Inside a polygon means an odd number of crossings of its rings by a ray, which is the even-odd rule for
+// polygons whose rings cross.
+func overlay(groups [][]planarPolygon, keep func(inside []bool) bool) []planarPolygon {
+	var polygons []planarPolygon
+	var group []int
+	for g, members := range groups {
+		for _, polygon := range members {
+			if len(polygon) > 0 {
+				polygons = append(polygons, polygon)
+				group = append(group, g)
+			}
+		}
+	}
+	var edges []*overlayEdge
+	length := 0.0
+	extent := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for owner, polygon := range polygons {
+		for _, r := range polygon {
+			for i := 0; i+1 < len(r); i++ {
+				if r[i] != r[i+1] {
+					edges = append(edges, &overlayEdge{p: r[i], q: r[i+1], owner: owner})
+					length += math.Hypot(r[i+1][0]-r[i][0], r[i+1][1]-r[i][1])
+				}
+			}
+			bbox := planarPolygon{r}.bbox()
+			extent[0], extent[1] = math.Min(extent[0], bbox[0]), math.Min(extent[1], bbox[1])
+			extent[2], extent[3] = math.Max(extent[2], bbox[2]), math.Max(extent[3], bbox[3])
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	size := nodeEdges(edges, length, extent)
+	snap := newSnapper()
+	for _, e := range edges {
+		e.p, e.q = snap.snap(e.p), snap.snap(e.q)
+		for i, v := range e.splits {
+			e.splits[i] = snap.snap(v)
+		}
+	}
+
+	polygonIndex := newGridIndex(math.Max(size, math.Max(extent[2]-extent[0], extent[3]-extent[1])/256))
+	banded := make([]*bandedPolygon, len(polygons))
+	for i, polygon := range polygons {
+		bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, r := range polygon {
+			b := planarPolygon{r}.bbox()
+			bbox[0], bbox[1] = math.Min(bbox[0], b[0]), math.Min(bbox[1], b[1])
+			bbox[2], bbox[3] = math.Max(bbox[2], b[2]), math.Max(bbox[3], b[3])
+		}
+		polygonIndex.insert(i, bbox)
+		banded[i] = newBandedPolygon(polygon)
+	}
+	inside := make([]bool, len(groups))
+	inResult := func(v vec) bool {
+		for g := range inside {
+			inside[g] = false
+		}
+		polygonIndex.query([4]float64{v[0], v[1], v[0], v[1]}, func(i int) {
+			if !inside[group[i]] && banded[i].locate(v) == interior {
+				inside[group[i]] = true
+			}
+		})
+		return keep(inside)
+	}
+	kept := map[[2]vec]bool{}
+	considered := map[[2]vec]bool{}
+	for _, e := range edges {
+		for _, piece := range e.pieces() {
+			reverse := [2]vec{piece[1], piece[0]}
+			if considered[piece] || considered[reverse] {
+				continue
+			}
+			considered[piece] = true
+			d := piece[1].sub(piece[0])
+			// probe just beside the middle of the piece, farther than the tolerance of locate
+			offset := 100 * overlayTolerance / math.Hypot(d[0], d[1])
+			mid := vec{(piece[0][0] + piece[1][0]) / 2, (piece[0][1] + piece[1][1]) / 2}
+			left := inResult(vec{mid[0] - d[1]*offset, mid[1] + d[0]*offset})
+			right := inResult(vec{mid[0] + d[1]*offset, mid[1] - d[0]*offset})
+			if left && !right {
+				kept[piece] = true
+			} else if right && !left {
+				kept[reverse] = true
+			}
+		}
+	}
+	return assemblePolygons(traceRings(kept))
+}
+
+// nodeEdges splits edges where they meet, finding the edges which may meet with a grid index whose cells
+// fit a couple of average edges, but no more than 1024 of them across the extent of the edges. It returns
+// the size of the cells.
+func nodeEdges(edges []*overlayEdge, length float64, extent [4]float64) float64 {
+	index := newGridIndex(math.Max(2*length/float64(len(edges)), math.Max(extent[2]-extent[0], extent[3]-extent[1])/1024))
+	for i, e := range edges {
+		index.insert(i, e.bbox())
+	}
+	seen := make([]int, len(edges))
+	for i, e := range edges {
+		index.query(e.bbox(), func(j int) {
+			if j > i && seen[j] != i+1 {
+				seen[j] = i + 1
+				e.split(edges[j])
+			}
+		})
+	}
+	return index.size
+}
+
+// snapper merges the nodes within the overlay tolerance of each other into the first of them it's given,
+// so the pieces of edges whose ends or crossings are a rounding error apart join in the graph rather than
+// being linked by pieces too short to have a direction
+type snapper struct {
+	cells   map[[2]float64][]vec
+	snapped map[vec]vec
+}
+
+func newSnapper() *snapper {
+	return &snapper{cells: map[[2]float64][]vec{}, snapped: map[vec]vec{}}
+}
+
+func (s *snapper) snap(v vec) vec {
+	if w, ok := s.snapped[v]; ok {
+		return w
+	}
+	x, y := math.Floor(v[0]/overlayTolerance), math.Floor(v[1]/overlayTolerance)
+	for dx := -1.0; dx <= 1; dx++ {
+		for dy := -1.0; dy <= 1; dy++ {
+			for _, w := range s.cells[[2]float64{x + dx, y + dy}] {
+				if math.Hypot(v[0]-w[0], v[1]-w[1]) <= overlayTolerance {
+					s.snapped[v] = w
+					return w
+				}
+			}
+		}
+	}
+	s.cells[[2]float64{x, y}] = append(s.cells[[2]float64{x, y}], v)
+	s.snapped[v] = v
+	return v
+}
+
+// traceRings joins directed pieces into closed rings. At a node where rings touch, the ring continues along
+// the first piece clockwise from where it came, which keeps the interior of each ring on its left and
+// separates rings touching at a node.
+func traceRings(pieces map[[2]vec]bool) []ring {
+	outgoing := map[vec][][2]vec{}
+	for piece := range pieces {
+		outgoing[piece[0]] = append(outgoing[piece[0]], piece)
+	}
+	// map iteration order is random, sort to trace the same rings every time
+	starts := make([][2]vec, 0, len(pieces))
+	for piece := range pieces {
+		starts = append(starts, piece)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		a, b := starts[i], starts[j]
+		for k := 0; k < 2; k++ {
+			for l := 0; l < 2; l++ {
+				if a[k][l] != b[k][l] {
+					return a[k][l] < b[k][l]
+				}
+			}
+		}
+		return false
+	})
+	used := map[[2]vec]bool{}
+	var rings []ring
+	for _, start := range starts {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		r := ring{start[0], start[1]}
+		current := start
+		for {
+			back := current[0].sub(current[1])
+			backAngle := math.Atan2(back[1], back[0])
+			var next [2]vec
+			best := math.Inf(1)
+			for _, candidate := range outgoing[current[1]] {
+				if used[candidate] && candidate != start {
+					continue
+				}
+				d := candidate[1].sub(candidate[0])
+				turn := backAngle - math.Atan2(d[1], d[0])
+				for turn <= 0 {
+					turn += 2 * math.Pi
+				}
+				if turn < best {
+					next, best = candidate, turn
+				}
+			}
+			if math.IsInf(best, 1) || next == start {
+				break
+			}
+			used[next] = true
+			r = append(r, next[1])
+			current = next
+		}
+		if r[0] == r[len(r)-1] && len(r) >= 4 {
+			rings = append(rings, simplifyCollinear(r))
+		}
+	}
+	return rings
+}
+
+// simplifyCollinear drops the vertices of a ring in the middle of a straight stretch
+func simplifyCollinear(r ring) ring {
+	if len(r) < 4 {
+		return r
+	}
+	vertices := r[:len(r)-1]
+	var simplified ring
+	for i, v := range vertices {
+		previous := vertices[(i+len(vertices)-1)%len(vertices)]
+		next := vertices[(i+1)%len(vertices)]
+		a, b := v.sub(previous), next.sub(v)
+		if math.Abs(cross(a, b)) > 1e-12*math.Hypot(a[0], a[1])*math.Hypot(b[0], b[1]) || a[0]*b[0]+a[1]*b[1] < 0 {
+			simplified = append(simplified, v)
+		}
+	}
+	if len(simplified) < 3 {
+		return r
+	}
+	return append(simplified, simplified[0])
+}
+
+// assemblePolygons nests rings into polygons: counterclockwise rings are shells and clockwise rings are
+// holes of the smallest shell around them
+func assemblePolygons(rings []ring) []planarPolygon {
+	var shells []planarPolygon
+	var holes []ring
+	for _, r := range rings {
+		switch area := r.signedArea(); {
+		case area > 0:
+			shells = append(shells, planarPolygon{r})
+		case area < 0:
+			holes = append(holes, r)
+		}
+	}
+	sort.Slice(shells, func(i, j int) bool { return shells[i][0].signedArea() < shells[j][0].signedArea() })
+	for _, hole := range holes {
+		// a point just beside the hole's first edge, in the polygon it belongs to
+		d := hole[1].sub(hole[0])
+		offset := 1e-6
+		inside := vec{(hole[0][0]+hole[1][0])/2 - d[1]*offset, (hole[0][1]+hole[1][1])/2 + d[0]*offset}
+		for i, shell := range shells {
+			if shell[0].contains(inside) {
+				shells[i] = append(shell, hole)
+				break
+			}
+		}
+	}
+	return shells
+}
@@ -0,0 +1,128 @@
+package spatially
+
+import (
+	"math"
+	"testing"
+)
+
+func square(x, y, size float64) planarPolygon {
+	return planarPolygon{ring{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}}
+}
+
+func planarArea(polygons []planarPolygon) float64 {
+	area := 0.0
+	for _, polygon := range polygons {
+		for _, r := range polygon {
+			area += r.signedArea()
+		}
+	}
+	return area
+}
+
+func TestUnionPolygons(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		polygons []planarPolygon
+		shells   int
+		holes    int
+		area     float64
+		vertices int
+	}{
+		{"overlapping", []planarPolygon{square(0, 0, 2), square(1, 1, 2)}, 1, 0, 7, 9},
+		{"shared edge", []planarPolygon{square(0, 0, 1), square(1, 0, 1)}, 1, 0, 2, 5},
+		{"identical", []planarPolygon{square(0, 0, 1), square(0, 0, 1)}, 1, 0, 1, 5},
+		{"contained", []planarPolygon{square(0, 0, 4), square(1, 1, 1)}, 1, 0, 16, 5},
+		{"disjoint", []planarPolygon{square(0, 0, 1), square(3, 3, 1)}, 2, 0, 2, 10},
+		{"touching corners", []planarPolygon{square(0, 0, 1), square(1, 1, 1)}, 2, 0, 2, 10},
+		{"clockwise input", []planarPolygon{square(0, 0, 1)[0].reversed()[:].toPolygon()}, 1, 0, 1, 5},
+		// four bars around a square leave a hole
+		{"frame", []planarPolygon{
+			{ring{{0, 0}, {3, 0}, {3, 1}, {0, 1}, {0, 0}}},
+			{ring{{2, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 0}}},
+			{ring{{0, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 2}}},
+			{ring{{0, 0}, {1, 0}, {1, 3}, {0, 3}, {0, 0}}},
+		}, 1, 1, 8, 10},
+		// the hole of the first polygon is partly filled by the second
+		{"hole", []planarPolygon{
+			{ring{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}, ring{{1, 1}, {1, 3}, {3, 3}, {3, 1}, {1, 1}}},
+			square(0.5, 0.5, 1),
+		}, 1, 1, 16 - 4 + 0.25, 12},
+	} {
+		union := unionPolygons(test.polygons)
+		holes, vertices := 0, 0
+		for _, polygon := range union {
+			holes += len(polygon) - 1
+			for _, r := range polygon {
+				vertices += len(r)
+				if r[0] != r[len(r)-1] {
+					t.Errorf("%s: ring isn't closed %v", test.name, r)
+				}
+			}
+		}
+		if len(union) != test.shells || holes != test.holes || vertices != test.vertices {
+			t.Errorf("%s: expected %d shells, %d holes and %d vertices, got %d, %d and %d: %v", test.name,
+				test.shells, test.holes, test.vertices, len(union), holes, vertices, union)
+		}
+		if area := planarArea(union); math.Abs(area-test.area) > 1e-9 {
+			t.Errorf("%s: expected an area of %f, got %f", test.name, test.area, area)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	intersection := func(inside []bool) bool { return inside[0] && inside[1] }
+	difference := func(inside []bool) bool { return inside[0] && !inside[1] }
+	withHole := planarPolygon{ring{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}, ring{{1, 1}, {1, 3}, {3, 3}, {3, 1}, {1, 1}}}
+	for _, test := range []struct {
+		name     string
+		a, b     planarPolygon
+		keep     func(inside []bool) bool
+		shells   int
+		holes    int
+		area     float64
+		vertices int
+	}{
+		{"overlapping intersection", square(0, 0, 2), square(1, 1, 2), intersection, 1, 0, 1, 5},
+		{"overlapping difference", square(0, 0, 2), square(1, 1, 2), difference, 1, 0, 3, 7},
+		{"shared edge intersection", square(0, 0, 1), square(1, 0, 1), intersection, 0, 0, 0, 0},
+		{"shared edge difference", square(0, 0, 1), square(1, 0, 1), difference, 1, 0, 1, 5},
+		{"touching corners intersection", square(0, 0, 1), square(1, 1, 1), intersection, 0, 0, 0, 0},
+		{"touching corners difference", square(0, 0, 1), square(1, 1, 1), difference, 1, 0, 1, 5},
+		// the edges along y = 0 overlap from x = 1 to 2
+		{"collinear intersection", square(0, 0, 2), planarPolygon{ring{{1, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 0}}}, intersection, 1, 0, 1, 5},
+		{"collinear difference", square(0, 0, 2), planarPolygon{ring{{1, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 0}}}, difference, 1, 0, 3, 7},
+		{"identical difference", square(0, 0, 1), square(0, 0, 1), difference, 0, 0, 0, 0},
+		// the second polygon covers a corner of the hole of the first
+		{"hole intersection", withHole, square(0.5, 0.5, 1), intersection, 1, 0, 0.75, 7},
+		{"hole difference", withHole, square(0.5, 0.5, 1), difference, 1, 1, 16 - 4 - 0.75, 14},
+		{"in the hole intersection", withHole, square(1.5, 1.5, 1), intersection, 0, 0, 0, 0},
+		{"in the hole difference", withHole, square(1.5, 1.5, 1), difference, 1, 1, 12, 10},
+		{"hole filled difference", square(1, 1, 2), withHole, difference, 1, 0, 4, 5},
+	} {
+		result := overlay([][]planarPolygon{{test.a}, {test.b}}, test.keep)
+		holes, vertices := 0, 0
+		for _, polygon := range result {
+			holes += len(polygon) - 1
+			for i, r := range polygon {
+				vertices += len(r)
+				if r[0] != r[len(r)-1] {
+					t.Errorf("%s: ring isn't closed %v", test.name, r)
+				}
+				if area := r.signedArea(); i == 0 && area <= 0 || i > 0 && area >= 0 {
+					t.Errorf("%s: ring %d has the wrong orientation %v", test.name, i, r)
+				}
+			}
+		}
+		if len(result) != test.shells || holes != test.holes || vertices != test.vertices {
+			t.Errorf("%s: expected %d shells, %d holes and %d vertices, got %d, %d and %d: %v", test.name,
+				test.shells, test.holes, test.vertices, len(result), holes, vertices, result)
+		}
+		if area := planarArea(result); math.Abs(area-test.area) > 1e-9 {
+			t.Errorf("%s: expected an area of %f, got %f", test.name, test.area, area)
+		}
+	}
+}
+
+func (r ring) toPolygon() planarPolygon {
+	return planarPolygon{r}
+}
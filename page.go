@@ -0,0 +1,310 @@
+package spatially
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// SortOrder is the order of the features of a page, sent to the API by name
+type SortOrder string
+
+// Sort orders
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// PageOptions selects a page of the features of a query. The zero value is the first page, of the
+// server's default size and in its default order.
+type PageOptions struct {
+	// Limit is the most features in a page, the server's default when it's 0
+	Limit int `json:"limit,omitempty"`
+	// Offset skips features from the start of the query, e.g. to jump to a page. It can't be used
+	// with PageToken.
+	Offset int `json:"offset,omitempty"`
+	// PageToken continues the query from the page it was returned with. It's only valid for the same
+	// layer, spatial constraint, filters and sort.
+	PageToken string `json:"pageToken,omitempty"`
+	// SortBy is the property the features are ordered by, their ID by default
+	SortBy string `json:"sortBy,omitempty"`
+	// Order is ascending by default
+	Order SortOrder `json:"order,omitempty"`
+}
+
+// validate checks the options before they're sent
+func (p *PageOptions) validate() error {
+	if p.Limit < 0 || p.Offset < 0 {
+		return fmt.Errorf("page limit and offset can't be negative, got %d and %d", p.Limit, p.Offset)
+	}
+	if p.Offset != 0 && p.PageToken != "" {
+		return fmt.Errorf("page offset can't be used with a page token")
+	}
+	switch p.Order {
+	case "", SortAscending, SortDescending:
+		return nil
+	}
+	return fmt.Errorf("unknown sort order %q", p.Order)
+}
+
+// featuresRequest builds and checks the request for the features of a layer. The spatial constraint
+// and the page options may be nil, and several filters all have to select a feature.
+func featuresRequest(layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters []*Filter) (request getFeaturesRequest, err error) {
+	request.LayerID = layerID
+	if spatialConstraint != nil {
+		if err = spatialConstraint.validate(); err != nil {
+			return
+		}
+		request.SpatialConstraint = spatialConstraint
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		request.Filter = filters[0]
+	default:
+		request.Filter = And(filters...)
+	}
+	if request.Filter != nil {
+		if err = request.Filter.validate(); err != nil {
+			return request, errors.Wrap(err, "filter")
+		}
+	}
+	if options != nil {
+		if err = options.validate(); err != nil {
+			return request, errors.Wrap(err, "page options")
+		}
+		request.PageOptions = *options
+	}
+	return
+}
+
+// GetPage - Given a layer id, retrieves a page of the features that belong to it and satisfy the spatial
+// constraint and the filters, and updates the slice receiver. The spatial constraint may be nil for all
+// the features of the layer. The returned token is set as PageOptions.PageToken to get the next page, and
+// is empty after the last page.
+func (f *Features) GetPage(db API, layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters ...*Filter) (nextPageToken string, err error) {
+	return f.GetPageContext(context.Background(), db, layerID, spatialConstraint, options, filters...)
+}
+
+// GetPageContext is GetPage with a context used to cancel the request or set its deadline
+func (f *Features) GetPageContext(ctx context.Context, db API, layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters ...*Filter) (nextPageToken string, err error) {
+	it := newFeatureIterator(ctx, db, "get features page", layerID, spatialConstraint, options, filters)
+	it.onePage = true
+	return f.read(it)
+}
+
+// read replaces the receiver with the features of the iterator and returns the token of the page after them
+func (f *Features) read(it *FeatureIterator) (nextPageToken string, err error) {
+	defer it.Close()
+	features := NewFeatures()
+	for {
+		feature, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		features = append(features, feature)
+	}
+	*f = features
+	return it.nextPageToken, nil
+}
+
+// FeatureIterator reads the features of a query one at a time. Features are decoded as they arrive and
+// the next page is requested when one runs out, so layers of any size are read in constant memory.
+// A page is either an array of features, or an object with its features and the token of the next page,
+// {"features": [...], "nextPageToken": "..."}. A FeatureIterator is not safe for concurrent use.
+type FeatureIterator struct {
+	ctx       context.Context
+	db        API
+	operation string
+	request   getFeaturesRequest
+	// onePage stops at the end of the first page
+	onePage bool
+
+	body          io.ReadCloser
+	decoder       *json.Decoder
+	paged         bool
+	inFeatures    bool
+	nextPageToken string
+	err           error
+}
+
+// IterateFeatures iterates over the features of a layer which satisfy the spatial constraint and the
+// filters, starting from the page the options select. The spatial constraint and the options may be nil,
+// and PageOptions.Limit is the number of features requested at a time. Requests are only sent by Next.
+func IterateFeatures(db API, layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters ...*Filter) *FeatureIterator {
+	return IterateFeaturesContext(context.Background(), db, layerID, spatialConstraint, options, filters...)
+}
+
+// IterateFeaturesContext is IterateFeatures with a context used to cancel the requests or set their deadline
+func IterateFeaturesContext(ctx context.Context, db API, layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters ...*Filter) *FeatureIterator {
+	return newFeatureIterator(ctx, db, "iterate features", layerID, spatialConstraint, options, filters)
+}
+
+func newFeatureIterator(ctx context.Context, db API, operation string, layerID string, spatialConstraint *SpatialConstraint, options *PageOptions, filters []*Filter) *FeatureIterator {
+	it := &FeatureIterator{
+		ctx:       ctx,
+		db:        db,
+		operation: operation,
+	}
+	request, err := featuresRequest(layerID, spatialConstraint, options, filters)
+	if err != nil {
+		it.err = errors.Wrap(err, operation)
+	}
+	it.request = request
+	return it
+}
+
+// Next returns the next feature. At the end of the query Next returns a nil feature and io.EOF. After an
+// error Next keeps returning it, the features already read aren't read again.
+func (it *FeatureIterator) Next() (*Feature, error) {
+	for it.err == nil {
+		switch {
+		case it.decoder == nil:
+			it.err = it.open()
+		case it.inFeatures && it.decoder.More():
+			feature := NewFeature()
+			if err := it.decoder.Decode(feature); err != nil {
+				it.err = errors.Wrap(err, it.operation+" parse response body json")
+				break
+			}
+			return feature, nil
+		default:
+			it.err = it.endPage()
+		}
+	}
+	it.closeBody()
+	return nil, it.err
+}
+
+// Close releases the response being read, for when the features aren't read to the end. Next returns
+// io.EOF after Close.
+func (it *FeatureIterator) Close() error {
+	if it.err == nil {
+		it.err = io.EOF
+	}
+	return it.closeBody()
+}
+
+func (it *FeatureIterator) closeBody() error {
+	if it.body == nil {
+		return nil
+	}
+	err := it.body.Close()
+	it.body = nil
+	it.decoder = nil
+	return err
+}
+
+// open requests the page and reads up to its first feature
+func (it *FeatureIterator) open() error {
+	j, err := json.Marshal(it.request)
+	if err != nil {
+		return errors.Wrap(err, it.operation+" json marshal request body")
+	}
+	request, err := clientOf(it.db).NewRequestContext(it.ctx, "POST", "/spatialdb/features", bytes.NewReader(j))
+	if err != nil {
+		return errors.Wrap(err, it.operation+" prepare http request")
+	}
+	resp, err := send(it.db, idempotent(request))
+	if err != nil {
+		return errors.Wrap(err, it.operation+" http post")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Wrap(err, it.operation+" read response body")
+		}
+		return newAPIError(request, resp, responseBody)
+	}
+	it.body = resp.Body
+	it.decoder = json.NewDecoder(resp.Body)
+	it.paged, it.inFeatures, it.nextPageToken = false, false, ""
+	token, err := it.decoder.Token()
+	if err != nil {
+		return errors.Wrap(err, it.operation+" parse response body json")
+	}
+	switch token {
+	case json.Delim('['):
+		it.inFeatures = true
+	case json.Delim('{'):
+		it.paged = true
+		return it.readKeys()
+	case nil:
+	default:
+		return fmt.Errorf("%s response body is %v, not features", it.operation, token)
+	}
+	return nil
+}
+
+// readKeys reads the keys of a page object up to its features or its end, keeping the next page token
+func (it *FeatureIterator) readKeys() error {
+	for it.decoder.More() {
+		key, err := it.decoder.Token()
+		if err != nil {
+			return errors.Wrap(err, it.operation+" parse response body json")
+		}
+		switch key {
+		case "features":
+			token, err := it.decoder.Token()
+			if err != nil {
+				return errors.Wrap(err, it.operation+" parse response body json")
+			}
+			if token == nil {
+				continue
+			}
+			if token != json.Delim('[') {
+				return fmt.Errorf("%s response features are %v, not an array", it.operation, token)
+			}
+			it.inFeatures = true
+			return nil
+		case "nextPageToken":
+			if err := it.decoder.Decode(&it.nextPageToken); err != nil {
+				return errors.Wrap(err, it.operation+" parse response body json")
+			}
+		default:
+			var skipped json.RawMessage
+			if err := it.decoder.Decode(&skipped); err != nil {
+				return errors.Wrap(err, it.operation+" parse response body json")
+			}
+		}
+	}
+	if _, err := it.decoder.Token(); err != nil {
+		return errors.Wrap(err, it.operation+" parse response body json")
+	}
+	it.paged = false
+	return nil
+}
+
+// endPage reads the rest of the page after its features and moves on to the next page, if there's one
+func (it *FeatureIterator) endPage() error {
+	if it.inFeatures {
+		if _, err := it.decoder.Token(); err != nil {
+			return errors.Wrap(err, it.operation+" parse response body json")
+		}
+		it.inFeatures = false
+	}
+	if it.paged {
+		// the object can have more keys after its features, possibly more features
+		return it.readKeys()
+	}
+	it.closeBody()
+	if it.onePage || it.nextPageToken == "" {
+		return io.EOF
+	}
+	if it.nextPageToken == it.request.PageToken {
+		return fmt.Errorf("%s got the page token %q it sent", it.operation, it.nextPageToken)
+	}
+	it.request.PageToken = it.nextPageToken
+	it.request.Offset = 0
+	return nil
+}
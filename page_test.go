@@ -0,0 +1,225 @@
+package spatially
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func pageFeature(i int) *geojson.Feature {
+	feature := geojson.NewPointFeature([]float64{-71.06, 42.35})
+	feature.ID = fmt.Sprintf("store %d", i)
+	return feature
+}
+
+// newPagedAPI serves 7 features 3 at a time, with the next page token before or after the features
+func newPagedAPI(t *testing.T, requests *[]getFeaturesRequest) (API, func()) {
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		var request getFeaturesRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		*requests = append(*requests, request)
+		start := request.Offset
+		if request.PageToken != "" {
+			fmt.Sscanf(request.PageToken, "after %d", &start)
+		}
+		var features []*geojson.Feature
+		for i := start; i < 7 && i < start+3; i++ {
+			features = append(features, pageFeature(i))
+		}
+		j, _ := json.Marshal(features)
+		next := ""
+		if start+3 < 7 {
+			next = fmt.Sprintf("after %d", start+3)
+		}
+		if start == 0 {
+			fmt.Fprintf(w, `{"nextPageToken": %q, "total": {"features": 7}, "features": %s}`, next, j)
+			return
+		}
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "features": %s, "nextPageToken": %q}`, j, next)
+	})
+	return sdb, server.Close
+}
+
+func TestIterateFeatures(t *testing.T) {
+	var requests []getFeaturesRequest
+	sdb, closeServer := newPagedAPI(t, &requests)
+	defer closeServer()
+
+	it := IterateFeatures(sdb, "layer", nil, &PageOptions{Limit: 3, SortBy: "name", Order: SortDescending}, Exists("name"))
+	defer it.Close()
+	var ids []interface{}
+	for {
+		feature, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, feature.ID)
+	}
+	if fmt.Sprint(ids) != "[store 0 store 1 store 2 store 3 store 4 store 5 store 6]" {
+		t.Error("Expected the 7 features in order, got", ids)
+	}
+	if len(requests) != 3 {
+		t.Fatal("Expected 3 pages, got", len(requests))
+	}
+	for i, request := range requests {
+		if request.Limit != 3 || request.SortBy != "name" || request.Order != SortDescending || request.Filter == nil {
+			t.Errorf("Expected the options and filter in every request, got %+v", request)
+		}
+		if expected := []string{"", "after 3", "after 6"}[i]; request.PageToken != expected {
+			t.Errorf("Expected page token %q, got %q", expected, request.PageToken)
+		}
+	}
+	if _, err := it.Next(); err != io.EOF {
+		t.Error("Expected io.EOF after the last feature, got", err)
+	}
+
+	features := NewFeatures()
+	if err := features.GetByLayer(sdb, "layer"); err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 7 || len(requests) != 6 {
+		t.Errorf("Expected every page of the layer, got %d features in %d requests", len(features), len(requests)-3)
+	}
+	if err := features.GetBySpatialConstraint(sdb, "layer", &SpatialConstraint{WKT: "POINT(-71.06 42.35)", Radius: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 7 || len(requests) != 9 {
+		t.Errorf("Expected every page of the features, got %d features in %d requests", len(features), len(requests)-6)
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	var requests []getFeaturesRequest
+	sdb, closeServer := newPagedAPI(t, &requests)
+	defer closeServer()
+
+	features := NewFeatures()
+	constraint := &SpatialConstraint{WKT: "POINT(-71.06 42.35)", Radius: 1000}
+	next, err := features.GetPage(sdb, "layer", constraint, &PageOptions{Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 3 || features[0].ID != "store 2" || next != "after 5" {
+		t.Errorf("Expected stores 2 to 4 and a page token, got %d features and %q", len(features), next)
+	}
+	if requests[0].SpatialConstraint == nil || requests[0].Offset != 2 {
+		t.Errorf("Unexpected request %+v", requests[0])
+	}
+	next, err = features.GetPage(sdb, "layer", constraint, &PageOptions{PageToken: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 || features[1].ID != "store 6" || next != "" || len(requests) != 2 {
+		t.Errorf("Expected the last 2 stores without a page token, got %d features and %q", len(features), next)
+	}
+}
+
+func TestIterateFeaturesStreams(t *testing.T) {
+	release := make(chan struct{})
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		first, _ := json.Marshal(pageFeature(0))
+		second, _ := json.Marshal(pageFeature(1))
+		fmt.Fprintf(w, "[%s,", first)
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprintf(w, "%s]", second)
+	})
+	defer server.Close()
+
+	it := IterateFeatures(sdb, "layer", nil, nil)
+	defer it.Close()
+	feature, err := it.Next()
+	if err != nil || feature.ID != "store 0" {
+		t.Fatal("Expected the first feature before the rest of the response, got", feature, err)
+	}
+	close(release)
+	if feature, err = it.Next(); err != nil || feature.ID != "store 1" {
+		t.Error("Expected the second feature, got", feature, err)
+	}
+	if _, err = it.Next(); err != io.EOF {
+		t.Error("Expected io.EOF, got", err)
+	}
+}
+
+func TestIterateFeaturesErrors(t *testing.T) {
+	var requests int
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		var request getFeaturesRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		j, _ := json.Marshal([]*geojson.Feature{pageFeature(requests)})
+		switch request.LayerID {
+		case "failing":
+			if request.PageToken != "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"message": "database unavailable"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"features": %s, "nextPageToken": "next"}`, j)
+		case "looping":
+			fmt.Fprintf(w, `{"features": %s, "nextPageToken": "same"}`, j)
+		case "truncated":
+			fmt.Fprintf(w, `{"features": [%s`, j[1:len(j)-1])
+		}
+	})
+	defer server.Close()
+
+	for _, options := range []*PageOptions{{Limit: -1}, {Offset: 10, PageToken: "next"}, {Order: "random"}} {
+		if _, err := IterateFeatures(sdb, "layer", nil, options).Next(); err == nil || err == io.EOF {
+			t.Errorf("Expected an error for %+v, got %v", options, err)
+		}
+	}
+	if requests != 0 {
+		t.Error("Expected invalid options not to be sent")
+	}
+
+	it := IterateFeatures(sdb, "failing", nil, nil)
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := it.Next(); !hasStatusCode(err, http.StatusInternalServerError) {
+			t.Error("Expected the second page to fail, got", err)
+		}
+	}
+	features := NewFeatures()
+	if _, err := features.GetPage(sdb, "failing", nil, &PageOptions{PageToken: "next"}); !hasStatusCode(err, http.StatusInternalServerError) {
+		t.Error("Expected the page to fail, got", err)
+	}
+
+	it = IterateFeatures(sdb, "looping", nil, &PageOptions{PageToken: "same"})
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := it.Next(); err == nil || err == io.EOF {
+		t.Error("Expected an error for a repeated page token, got", err)
+	}
+
+	it = IterateFeatures(sdb, "truncated", nil, nil)
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := it.Next(); err == nil || err == io.EOF {
+		t.Error("Expected an error for a truncated response, got", err)
+	}
+
+	it = IterateFeatures(sdb, "looping", nil, nil)
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	it.Close()
+	if _, err := it.Next(); err != io.EOF {
+		t.Error("Expected io.EOF after Close, got", err)
+	}
+}
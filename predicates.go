@@ -0,0 +1,447 @@
+package spatially
+
+import (
+	"math"
+	"sort"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// predicateTolerance is the distance in degrees, about 0.1mm, under which points are considered to touch
+const predicateTolerance = 1e-9
+
+// Intersects reports whether a and b have at least a point in common. Predicates are evaluated locally in
+// longitude & latitude, where GeoJSON edges are straight lines, and treat empty geometries as disjoint.
+func Intersects(a, b *geojson.Geometry) bool {
+	m := relate(a, b)
+	return m[interior][interior] || m[interior][boundary] || m[boundary][interior] || m[boundary][boundary]
+}
+
+// Disjoint reports whether a and b have no point in common
+func Disjoint(a, b *geojson.Geometry) bool {
+	return !Intersects(a, b)
+}
+
+// Contains reports whether every point of b is in a and their interiors intersect, so a polygon doesn't
+// contain the edge of its boundary and a customer location on the border of an ATA isn't contained by it
+func Contains(a, b *geojson.Geometry) bool {
+	m := relate(a, b)
+	return m[interior][interior] && !m[exterior][interior] && !m[exterior][boundary]
+}
+
+// Within reports whether a is contained by b, see Contains
+func Within(a, b *geojson.Geometry) bool {
+	return Contains(b, a)
+}
+
+// Touches reports whether a and b intersect only at their boundaries, e.g. neighboring polygons sharing
+// an edge or a linestring ending on a polygon
+func Touches(a, b *geojson.Geometry) bool {
+	m := relate(a, b)
+	return !m[interior][interior] && (m[interior][boundary] || m[boundary][interior] || m[boundary][boundary])
+}
+
+// DWithin reports whether a and b are within a distance in meters of each other, measured along geodesics
+// between their closest points
+func DWithin(a, b *geojson.Geometry, meters float64) bool {
+	pa, pb := newParts(a), newParts(b)
+	if pa.empty() || pb.empty() {
+		return false
+	}
+	if Intersects(a, b) {
+		return true
+	}
+	return pa.distance(pb) <= meters
+}
+
+// location is where a point is relative to a geometry
+type location int
+
+const (
+	exterior location = iota
+	boundary
+	interior
+)
+
+// intersectionMatrix records which locations of a geometry a intersect which locations of a geometry b,
+// indexed [location in a][location in b], like a DE-9IM matrix without dimensions
+type intersectionMatrix [3][3]bool
+
+// relate computes the intersection matrix of a and b
+func relate(a, b *geojson.Geometry) intersectionMatrix {
+	var m intersectionMatrix
+	pa, pb := newParts(a), newParts(b)
+	if pa.empty() || pb.empty() {
+		return m
+	}
+	m[exterior][exterior] = true
+	pb.classify(pa, func(inB, inA location) { m[inA][inB] = true })
+	pa.classify(pb, func(inA, inB location) { m[inA][inB] = true })
+	return m
+}
+
+type segment struct {
+	p, q []float64
+	bbox [4]float64
+	// polygon and ring are the polygon and ring of the parts the segment is on, polygon is -1 for lines
+	polygon, ring int
+}
+
+func newSegment(p, q []float64) segment {
+	return segment{p, q, [4]float64{math.Min(p[0], q[0]), math.Min(p[1], q[1]), math.Max(p[0], q[0]), math.Max(p[1], q[1])}, -1, 0}
+}
+
+// near reports whether the bounds of two segments are within the tolerance of each other
+func (s segment) near(t segment) bool {
+	return s.bbox[0] <= t.bbox[2]+predicateTolerance && t.bbox[0] <= s.bbox[2]+predicateTolerance &&
+		s.bbox[1] <= t.bbox[3]+predicateTolerance && t.bbox[1] <= s.bbox[3]+predicateTolerance
+}
+
+// parts is a geometry broken into the points, linestrings and polygons predicates work on
+type parts struct {
+	points   [][]float64
+	lines    [][][]float64
+	polygons [][][][]float64
+	// lineBoundary are the endpoints of linestrings, those shared by an even number of linestrings excluded
+	lineBoundary [][]float64
+	segments     []segment
+	// index finds the segments which may meet a segment, seen and stamp skip those it finds twice
+	index *gridIndex
+	seen  []int
+	stamp int
+	// bands are the segments in each horizontal band of the parts, those a point can be on or a ray cast
+	// from it can cross, like the bands of a bandedPolygon
+	extent        [4]float64
+	south, height float64
+	bands         [][]int
+	// onRings and oddRings are buffers of locateIn
+	onRings, oddRings [][2]int
+}
+
+func newParts(g *geojson.Geometry) *parts {
+	p := &parts{}
+	p.points, p.lines, p.polygons = flatten(g)
+	var endpoints [][]float64
+	for _, line := range p.lines {
+		if len(line) > 0 {
+			endpoints = append(endpoints, line[0], line[len(line)-1])
+		}
+		p.segments = appendSegments(p.segments, line, -1, 0)
+	}
+	for _, e := range endpoints {
+		count := 0
+		for _, other := range endpoints {
+			if samePoint(e, other) {
+				count++
+			}
+		}
+		if count%2 == 1 && !p.isLineBoundary(e) {
+			p.lineBoundary = append(p.lineBoundary, e)
+		}
+	}
+	for i, polygon := range p.polygons {
+		for j, ring := range polygon {
+			p.segments = appendSegments(p.segments, ring, i, j)
+		}
+	}
+	p.indexSegments()
+	return p
+}
+
+func appendSegments(segments []segment, line [][]float64, polygon, ring int) []segment {
+	for i := 0; i+1 < len(line); i++ {
+		s := newSegment(line[i], line[i+1])
+		s.polygon, s.ring = polygon, ring
+		segments = append(segments, s)
+	}
+	return segments
+}
+
+// indexSegments builds the grid index and the bands of the segments. The cells of the index fit a couple
+// of average segments, but no more than 1024 of them across the extent of the segments, like nodeEdges.
+func (p *parts) indexSegments() {
+	if len(p.segments) == 0 {
+		return
+	}
+	extent := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	length := 0.0
+	for _, s := range p.segments {
+		extent[0], extent[1] = math.Min(extent[0], s.bbox[0]), math.Min(extent[1], s.bbox[1])
+		extent[2], extent[3] = math.Max(extent[2], s.bbox[2]), math.Max(extent[3], s.bbox[3])
+		length += math.Hypot(s.q[0]-s.p[0], s.q[1]-s.p[1])
+	}
+	p.extent = extent
+	size := math.Max(2*length/float64(len(p.segments)), math.Max(extent[2]-extent[0], extent[3]-extent[1])/1024)
+	if size == 0 {
+		size = 1
+	}
+	p.index = newGridIndex(size)
+	for i, s := range p.segments {
+		p.index.insert(i, s.bbox)
+	}
+	p.seen = make([]int, len(p.segments))
+
+	count := len(p.segments)/4 + 1
+	p.south, p.height = extent[1], (extent[3]-extent[1])/float64(count)
+	if p.height == 0 {
+		p.height = 1
+	}
+	p.bands = make([][]int, count)
+	for i, s := range p.segments {
+		for j := p.band(s.bbox[1] - predicateTolerance); j <= p.band(s.bbox[3]+predicateTolerance); j++ {
+			p.bands[j] = append(p.bands[j], i)
+		}
+	}
+}
+
+func (p *parts) band(y float64) int {
+	return int(math.Max(0, math.Min(float64(len(p.bands)-1), math.Floor((y-p.south)/p.height))))
+}
+
+// near calls fn with the segments whose bounds are near bbox, once each
+func (p *parts) near(bbox [4]float64, fn func(t segment)) {
+	if p.index == nil {
+		return
+	}
+	p.stamp++
+	bbox = [4]float64{bbox[0] - predicateTolerance, bbox[1] - predicateTolerance, bbox[2] + predicateTolerance, bbox[3] + predicateTolerance}
+	p.index.query(bbox, func(i int) {
+		if p.seen[i] != p.stamp {
+			p.seen[i] = p.stamp
+			fn(p.segments[i])
+		}
+	})
+}
+
+func (p *parts) empty() bool {
+	return len(p.points) == 0 && len(p.lines) == 0 && len(p.polygons) == 0
+}
+
+func (p *parts) isLineBoundary(q []float64) bool {
+	for _, b := range p.lineBoundary {
+		if samePoint(b, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// locate finds where q is relative to the geometry. Interiors take precedence over boundaries, so a point on
+// a linestring lying inside a polygon of the same collection is in its interior.
+func (p *parts) locate(q []float64) location {
+	loc := p.locateIn(q, -1)
+	if loc == interior {
+		return interior
+	}
+	for _, point := range p.points {
+		if samePoint(point, q) {
+			return interior
+		}
+	}
+	return loc
+}
+
+// locateIn finds where q is relative to the polygon of the parts at index only, or to all of the polygons
+// and lines when only is -1. It looks only at the segments of the band of q, counting the crossings of a ray
+// cast from q with each ring.
+func (p *parts) locateIn(q []float64, only int) location {
+	if len(p.bands) == 0 || q[0] < p.extent[0]-predicateTolerance || q[0] > p.extent[2]+predicateTolerance ||
+		q[1] < p.extent[1]-predicateTolerance || q[1] > p.extent[3]+predicateTolerance {
+		return exterior
+	}
+	loc := exterior
+	// the rings q is on and the rings a ray cast from q crosses an odd number of times, reusing the
+	// buffers of previous calls
+	p.onRings, p.oddRings = p.onRings[:0], p.oddRings[:0]
+	for _, i := range p.bands[p.band(q[1])] {
+		s := &p.segments[i]
+		if only >= 0 && s.polygon != only {
+			continue
+		}
+		if q[0] >= s.bbox[0]-predicateTolerance && q[0] <= s.bbox[2]+predicateTolerance && onSegment(q, s.p, s.q) {
+			loc = boundary
+			if s.polygon < 0 {
+				if !p.isLineBoundary(q) {
+					return interior
+				}
+				continue
+			}
+			p.onRings = append(p.onRings, [2]int{s.polygon, s.ring})
+		}
+		if s.polygon >= 0 && (s.p[1] > q[1]) != (s.q[1] > q[1]) && q[0] < s.p[0]+(q[1]-s.p[1])*(s.q[0]-s.p[0])/(s.q[1]-s.p[1]) {
+			p.oddRings = toggleRing(p.oddRings, [2]int{s.polygon, s.ring})
+		}
+	}
+	// q is inside a polygon when it's inside its shell, not on its rings and not inside its holes
+	for _, shell := range p.oddRings {
+		if shell[1] != 0 {
+			continue
+		}
+		inside := true
+		for _, ring := range p.onRings {
+			inside = inside && ring[0] != shell[0]
+		}
+		for _, hole := range p.oddRings {
+			inside = inside && (hole[0] != shell[0] || hole[1] == 0)
+		}
+		if inside {
+			return interior
+		}
+	}
+	return loc
+}
+
+// toggleRing adds a ring to the rings or removes it if it's there already
+func toggleRing(rings [][2]int, ring [2]int) [][2]int {
+	for i, r := range rings {
+		if r == ring {
+			return append(rings[:i], rings[i+1:]...)
+		}
+	}
+	return append(rings, ring)
+}
+
+func samePoint(p, q []float64) bool {
+	return math.Abs(p[0]-q[0]) <= predicateTolerance && math.Abs(p[1]-q[1]) <= predicateTolerance
+}
+
+func onSegment(q, p1, p2 []float64) bool {
+	return planarSegmentDistance(q, p1, p2) <= predicateTolerance
+}
+
+// closestOnSegment returns the parameter of the point of the segment p1 p2 closest to q, between 0 and 1
+func closestOnSegment(q, p1, p2 []float64) float64 {
+	dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, ((q[0]-p1[0])*dx+(q[1]-p1[1])*dy)/l2))
+}
+
+func planarSegmentDistance(q, p1, p2 []float64) float64 {
+	t := closestOnSegment(q, p1, p2)
+	return math.Hypot(p1[0]+t*(p2[0]-p1[0])-q[0], p1[1]+t*(p2[1]-p1[1])-q[1])
+}
+
+func interpolate(p1, p2 []float64, t float64) []float64 {
+	return []float64{p1[0] + t*(p2[0]-p1[0]), p1[1] + t*(p2[1]-p1[1])}
+}
+
+// splitParameters returns the sorted parameters, from 0 to 1, at which s meets the segments of other
+func splitParameters(s segment, other *parts) []float64 {
+	params := []float64{0, 1}
+	rx, ry := s.q[0]-s.p[0], s.q[1]-s.p[1]
+	other.near(s.bbox, func(t segment) {
+		if !s.near(t) {
+			return
+		}
+		// the ends of t on s, which covers touching and overlapping segments
+		for _, e := range [][]float64{t.p, t.q} {
+			if onSegment(e, s.p, s.q) {
+				params = append(params, closestOnSegment(e, s.p, s.q))
+			}
+		}
+		sx, sy := t.q[0]-t.p[0], t.q[1]-t.p[1]
+		d := rx*sy - ry*sx
+		if d == 0 {
+			return
+		}
+		wx, wy := t.p[0]-s.p[0], t.p[1]-s.p[1]
+		u, v := (wx*sy-wy*sx)/d, (wx*ry-wy*rx)/d
+		if u > 0 && u < 1 && v >= 0 && v <= 1 {
+			params = append(params, u)
+		}
+	})
+	sort.Float64s(params)
+	return params
+}
+
+// classify reports the location in p and in other of every node and piece of p's parts, after splitting
+// p's segments where they meet other's
+func (p *parts) classify(other *parts, set func(inP, inOther location)) {
+	for _, q := range p.points {
+		set(interior, other.locate(q))
+	}
+	for _, line := range p.lines {
+		for i := 0; i+1 < len(line); i++ {
+			s := newSegment(line[i], line[i+1])
+			params := splitParameters(s, other)
+			for j, t := range params {
+				node := interpolate(s.p, s.q, t)
+				if (t == 0 || t == 1) && p.isLineBoundary(node) {
+					set(boundary, other.locate(node))
+				} else {
+					set(interior, other.locate(node))
+				}
+				if j > 0 && t > params[j-1] {
+					set(interior, other.locate(interpolate(s.p, s.q, (t+params[j-1])/2)))
+				}
+			}
+		}
+	}
+	for k, polygon := range p.polygons {
+		if len(other.polygons) == 0 {
+			set(interior, exterior)
+		}
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				s := newSegment(ring[i], ring[i+1])
+				length := math.Hypot(s.q[0]-s.p[0], s.q[1]-s.p[1])
+				if length == 0 {
+					continue
+				}
+				params := splitParameters(s, other)
+				for j, t := range params {
+					set(boundary, other.locate(interpolate(s.p, s.q, t)))
+					if j == 0 || t <= params[j-1] {
+						continue
+					}
+					mid := interpolate(s.p, s.q, (t+params[j-1])/2)
+					set(boundary, other.locate(mid))
+					// the interior of the polygon is on one side of its boundary, look just beside the piece
+					offset := math.Max(length*(t-params[j-1])*1e-6, 10*predicateTolerance)
+					nx, ny := -(s.q[1]-s.p[1])/length, (s.q[0]-s.p[0])/length
+					for _, side := range []float64{offset, -offset} {
+						q := []float64{mid[0] + side*nx, mid[1] + side*ny}
+						if p.locateIn(q, k) == interior {
+							set(interior, other.locate(q))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// distance returns the geodesic distance in meters between the closest points of two disjoint geometries
+func (p *parts) distance(other *parts) float64 {
+	closest := math.Inf(1)
+	measure := func(from, to *parts) {
+		for _, q := range from.vertices() {
+			for _, point := range to.points {
+				closest = math.Min(closest, geodesicDistance(q, point))
+			}
+			for _, s := range to.segments {
+				closest = math.Min(closest, geodesicDistance(q, interpolate(s.p, s.q, closestOnSegmentLocally(q, s.p, s.q))))
+			}
+		}
+	}
+	measure(p, other)
+	measure(other, p)
+	return closest
+}
+
+// closestOnSegmentLocally is closestOnSegment with longitudes scaled to the meters of q's latitude
+func closestOnSegmentLocally(q, p1, p2 []float64) float64 {
+	scale := math.Cos(q[1] * math.Pi / 180)
+	return closestOnSegment([]float64{q[0] * scale, q[1]}, []float64{p1[0] * scale, p1[1]}, []float64{p2[0] * scale, p2[1]})
+}
+
+func (p *parts) vertices() [][]float64 {
+	vertices := append([][]float64{}, p.points...)
+	for _, s := range p.segments {
+		vertices = append(vertices, s.p, s.q)
+	}
+	return vertices
+}
@@ -0,0 +1,141 @@
+package spatially
+
+import (
+	"math"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestPredicates(t *testing.T) {
+	square := "POLYGON((0 0,4 0,4 4,0 4,0 0))"
+	for _, test := range []struct {
+		a, b                                            string
+		intersects, contains, within, touches, disjoint bool
+	}{
+		// points and a polygon
+		{square, "POINT(2 2)", true, true, false, false, false},
+		{square, "POINT(4 2)", true, false, false, true, false},
+		{square, "POINT(5 2)", false, false, false, false, true},
+		{"POINT(2 2)", square, true, false, true, false, false},
+		// a hole
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(1 1,3 1,3 3,1 3,1 1))", "POINT(2 2)", false, false, false, false, true},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(1 1,3 1,3 3,1 3,1 1))", "POLYGON((1 1,3 1,3 3,1 3,1 1))", true, false, false, true, false},
+		// polygons
+		{square, "POLYGON((1 1,2 1,2 2,1 2,1 1))", true, true, false, false, false},
+		{square, square, true, true, true, false, false},
+		{square, "POLYGON((0 0,4 0,4 2,0 2,0 0))", true, true, false, false, false},
+		{square, "POLYGON((4 0,8 0,8 4,4 4,4 0))", true, false, false, true, false},
+		{square, "POLYGON((4 4,8 4,8 8,4 8,4 4))", true, false, false, true, false},
+		{square, "POLYGON((2 2,6 2,6 6,2 6,2 2))", true, false, false, false, false},
+		{square, "POLYGON((5 5,6 5,6 6,5 6,5 5))", false, false, false, false, true},
+		{"POLYGON((1 1,2 1,2 2,1 2,1 1))", square, true, false, true, false, false},
+		// a polygon surrounding a U shape's notch isn't within it
+		{"POLYGON((0 0,3 0,3 3,2 3,2 1,1 1,1 3,0 3,0 0))", "POLYGON((0.5 0.5,2.5 0.5,2.5 2.5,0.5 2.5,0.5 0.5))", true, false, false, false, false},
+		// linestrings
+		{square, "LINESTRING(1 1,3 3)", true, true, false, false, false},
+		{square, "LINESTRING(0 0,4 0)", true, false, false, true, false},
+		{square, "LINESTRING(2 2,6 2)", true, false, false, false, false},
+		{square, "LINESTRING(4 2,6 2)", true, false, false, true, false},
+		{square, "LINESTRING(0 5,5 5)", false, false, false, false, true},
+		{"LINESTRING(0 0,2 2)", "LINESTRING(0 2,2 0)", true, false, false, false, false},
+		{"LINESTRING(0 0,2 2)", "LINESTRING(2 2,4 0)", true, false, false, true, false},
+		{"LINESTRING(0 0,4 4)", "LINESTRING(1 1,2 2)", true, true, false, false, false},
+		{"LINESTRING(0 0,2 2)", "POINT(0 0)", true, false, false, true, false},
+		{"LINESTRING(0 0,2 2)", "POINT(1 1)", true, true, false, false, false},
+		// a closed linestring has no boundary
+		{"LINESTRING(0 0,1 0,1 1,0 0)", "POINT(0 0)", true, true, false, false, false},
+		// points
+		{"POINT(1 1)", "POINT(1 1)", true, true, true, false, false},
+		{"MULTIPOINT(1 1,2 2)", "POINT(1 1)", true, true, false, false, false},
+		{"POINT(1 1)", "POINT(1 2)", false, false, false, false, true},
+		// collections
+		{"GEOMETRYCOLLECTION(POINT(10 10)," + square + ")", "POINT(2 2)", true, true, false, false, false},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((2 0,3 0,3 1,2 1,2 0)))", "LINESTRING(0.5 0.5,2.5 0.5)", true, false, false, false, false},
+		// empty geometries
+		{square, "POINT EMPTY", false, false, false, false, true},
+	} {
+		a, b := mustWKT(t, test.a), mustWKT(t, test.b)
+		if got := Intersects(a, b); got != test.intersects {
+			t.Errorf("Expected Intersects(%s, %s) to be %v", test.a, test.b, test.intersects)
+		}
+		if got := Contains(a, b); got != test.contains {
+			t.Errorf("Expected Contains(%s, %s) to be %v", test.a, test.b, test.contains)
+		}
+		if got := Within(a, b); got != test.within {
+			t.Errorf("Expected Within(%s, %s) to be %v", test.a, test.b, test.within)
+		}
+		if got := Touches(a, b); got != test.touches {
+			t.Errorf("Expected Touches(%s, %s) to be %v", test.a, test.b, test.touches)
+		}
+		if got := Disjoint(a, b); got != test.disjoint {
+			t.Errorf("Expected Disjoint(%s, %s) to be %v", test.a, test.b, test.disjoint)
+		}
+	}
+}
+
+func TestDWithin(t *testing.T) {
+	for _, test := range []struct {
+		a, b   string
+		meters float64
+		within bool
+	}{
+		{"POINT(0 0)", "POINT(1 0)", 111320, true},
+		{"POINT(0 0)", "POINT(1 0)", 111318, false},
+		// the closest point is in the middle of the segment
+		{"POINT(0.5 0.001)", "LINESTRING(0 0,1 0)", 111, true},
+		{"POINT(0.5 0.001)", "LINESTRING(0 0,1 0)", 110, false},
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", "POINT(0.5 0.5)", 0, true},
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", "POLYGON((1.001 0,2 0,2 1,1.001 1,1.001 0))", 112, true},
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", "POLYGON((1.001 0,2 0,2 1,1.001 1,1.001 0))", 110, false},
+		{"POINT EMPTY", "POINT(0 0)", 1000, false},
+	} {
+		if got := DWithin(mustWKT(t, test.a), mustWKT(t, test.b), test.meters); got != test.within {
+			t.Errorf("Expected DWithin(%s, %s, %f) to be %v", test.a, test.b, test.meters, test.within)
+		}
+	}
+}
+
+// circlePolygon is a polygon of n vertices around a center, with an optional hole of half its radius
+func circlePolygon(x, y, radius float64, n int, hole bool) *geojson.Geometry {
+	ring := func(r float64) [][]float64 {
+		var points [][]float64
+		for i := 0; i <= n; i++ {
+			angle := 2 * math.Pi * float64(i%n) / float64(n)
+			points = append(points, []float64{x + r*math.Cos(angle), y + r*math.Sin(angle)})
+		}
+		return points
+	}
+	polygon := [][][]float64{ring(radius)}
+	if hole {
+		polygon = append(polygon, ring(radius/2))
+	}
+	return geojson.NewPolygonGeometry(polygon)
+}
+
+func TestPredicatesLargePolygons(t *testing.T) {
+	large := circlePolygon(0, 0, 1, 5000, true)
+	for _, test := range []struct {
+		b                              *geojson.Geometry
+		intersects, contains, disjoint bool
+	}{
+		// in the ring between the shell and the hole
+		{circlePolygon(0.75, 0, 0.1, 1000, false), true, true, false},
+		// in the hole
+		{circlePolygon(0, 0, 0.25, 1000, false), false, false, true},
+		// across the shell
+		{circlePolygon(1, 0, 0.1, 1000, false), true, false, false},
+		{circlePolygon(3, 0, 0.1, 1000, false), false, false, true},
+	} {
+		if Intersects(large, test.b) != test.intersects || Contains(large, test.b) != test.contains || Disjoint(large, test.b) != test.disjoint {
+			t.Errorf("Unexpected predicates for %v", test.b.Polygon[0][0])
+		}
+	}
+}
+
+func BenchmarkIntersectsLargePolygons(b *testing.B) {
+	large, small := circlePolygon(0, 0, 1, 50000, false), circlePolygon(1, 0, 0.1, 10000, false)
+	for i := 0; i < b.N; i++ {
+		Intersects(large, small)
+	}
+}
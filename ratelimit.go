@@ -0,0 +1,174 @@
+package spatially
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointFamily is a group of Spatially endpoints that is throttled together
+type EndpointFamily int
+
+const (
+	// EndpointSpatialDB are the layer and feature endpoints under /spatialdb
+	EndpointSpatialDB EndpointFamily = iota
+	// EndpointGrid are the population, popular times, distance sensitivity and demographics
+	// endpoints under /grid
+	EndpointGrid
+	// EndpointScience are the ATA endpoints under /ads/science
+	EndpointScience
+)
+
+// endpointFamily finds the family of an API path. The gateway isn't part of any family.
+func endpointFamily(path string) (EndpointFamily, bool) {
+	switch {
+	case strings.HasPrefix(path, "/spatialdb/"):
+		return EndpointSpatialDB, true
+	case strings.HasPrefix(path, "/grid/"):
+		return EndpointGrid, true
+	case strings.HasPrefix(path, "/ads/science/"):
+		return EndpointScience, true
+	}
+	return 0, false
+}
+
+// WithRateLimit limits the requests sent to an endpoint family to requestsPerSecond, allowing bursts
+// of up to burst requests. Requests wait for their turn, or until their context is done. A rate of 0 or
+// less removes the limit, and a burst under 1 is 1.
+func WithRateLimit(family EndpointFamily, requestsPerSecond float64, burst int) ClientOption {
+	return func(c *Client) {
+		if !(requestsPerSecond > 0) {
+			c.throttle(family).bucket = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		c.throttle(family).bucket = &tokenBucket{
+			rate:   requestsPerSecond,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			last:   time.Now(),
+		}
+	}
+}
+
+// WithMaxInFlight limits the number of requests to an endpoint family that are sent at the same
+// time. A request holds its slot until its response body is closed.
+func WithMaxInFlight(family EndpointFamily, max int) ClientOption {
+	return func(c *Client) {
+		if max < 1 {
+			max = 1
+		}
+		c.throttle(family).slots = make(chan struct{}, max)
+	}
+}
+
+// throttle holds the limits of an endpoint family
+type throttle struct {
+	bucket *tokenBucket
+	slots  chan struct{}
+}
+
+func (c *Client) throttle(family EndpointFamily) *throttle {
+	if c.throttles == nil {
+		c.throttles = map[EndpointFamily]*throttle{}
+	}
+	if c.throttles[family] == nil {
+		c.throttles[family] = &throttle{}
+	}
+	return c.throttles[family]
+}
+
+// acquire waits until the request is allowed to be sent. The returned function gives its
+// in flight slot back.
+func (t *throttle) acquire(ctx context.Context) (release func(), err error) {
+	if t.bucket != nil {
+		if err := t.bucket.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if t.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-t.slots })
+	}, nil
+}
+
+// tokenBucket is a rate limiter that refills rate tokens per second up to burst
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token, waiting for it to be refilled if the bucket is empty
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mutex.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mutex.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		b.mutex.Lock()
+		b.tokens++
+		b.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// releaseBody gives the request's in flight slot back when the response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// sendThrottled sends a single attempt of the request, waiting for the limits of its endpoint family
+func (c *Client) sendThrottled(request *http.Request) (*http.Response, error) {
+	family, ok := endpointFamily(strings.TrimPrefix(request.URL.Path, c.basePath))
+	t := c.throttles[family]
+	if !ok || t == nil {
+		return c.httpClient.Do(request)
+	}
+	release, err := t.acquire(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
@@ -0,0 +1,125 @@
+package spatially
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	var inFlight, maxInFlight int32
+	api, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": GridPopulation{Residents: 10}})
+	}, WithMaxInFlight(EndpointGrid, 3))
+	defer server.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pop, err := Population(api, "POINT(-71.064156780428 42.35862883483673)", 150)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if pop.Residents != 10 {
+				t.Error("Invalid residents", pop.Residents)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 3 {
+		t.Error("Expected at most 3 requests in flight, got", maxInFlight)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	api, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/spatialdb/layers" {
+			json.NewEncoder(w).Encode(Layers{})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": GridPopulation{}})
+	}, WithRateLimit(EndpointSpatialDB, 50, 1))
+	defer server.Close()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		layers := NewLayers()
+		if err := layers.Get(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Error("Expected 6 requests at 50 per second to take at least 100ms, took", elapsed)
+	}
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := Population(api, "POINT(-71.064156780428 42.35862883483673)", 150); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Error("Grid requests should not be limited by the spatialdb rate, took", elapsed)
+	}
+}
+
+func TestRateLimitUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		client := NewClient(WithRateLimit(EndpointSpatialDB, 10, 1), WithRateLimit(EndpointSpatialDB, rate, 0))
+		if bucket := client.throttle(EndpointSpatialDB).bucket; bucket != nil {
+			t.Errorf("Expected a rate of %v to remove the limit, got %+v", rate, bucket)
+		}
+	}
+	api, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(Layers{})
+	}, WithRateLimit(EndpointSpatialDB, 0, 0))
+	defer server.Close()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		layers := NewLayers()
+		if err := layers.Get(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Error("Expected requests without a rate not to wait, took", elapsed)
+	}
+}
+
+func TestRateLimitContextCanceled(t *testing.T) {
+	bucket := &tokenBucket{rate: 1, burst: 1, tokens: 0, last: time.Now()}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.wait(ctx); err != context.DeadlineExceeded {
+		t.Error("Expected the wait to be canceled, got", err)
+	}
+}
+
+func TestEndpointFamily(t *testing.T) {
+	for path, expected := range map[string]EndpointFamily{
+		"/spatialdb/features": EndpointSpatialDB,
+		"/grid/stops":         EndpointGrid,
+		"/ads/science/ata":    EndpointScience,
+	} {
+		if family, ok := endpointFamily(path); !ok || family != expected {
+			t.Error("Invalid family for", path, family)
+		}
+	}
+	if _, ok := endpointFamily("/gateway/client"); ok {
+		t.Error("The gateway is not part of any family")
+	}
+}
@@ -0,0 +1,103 @@
+package spatially
+
+import (
+	"fmt"
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// SRIDs of the coordinate reference systems ReprojectToWGS84 converts from
+const (
+	// SRIDWGS84 is EPSG:4326, the longitude/latitude coordinates used by the Spatially API
+	SRIDWGS84 = 4326
+	// SRIDNAD83 is EPSG:4269, North American longitude/latitude coordinates
+	SRIDNAD83 = 4269
+	// SRIDWebMercator is EPSG:3857, the projection used by web maps
+	SRIDWebMercator = 3857
+)
+
+// WGS84 ellipsoid
+const (
+	wgs84SemiMajorAxis = 6378137.0
+	wgs84Flattening    = 1 / 298.257223563
+)
+
+// ReprojectToWGS84 returns a copy of g with its coordinates converted from the reference system with the
+// given SRID into EPSG:4326. Supported systems are EPSG:4326, EPSG:4269 (NAD83, within a meter of WGS84),
+// EPSG:3857 Web Mercator and its legacy codes 900913, 3785 and 102100, the WGS84 UTM zones EPSG:32601-32660
+// (north) and EPSG:32701-32760 (south) and the NAD83 UTM zones EPSG:26901-26923. Z and M values are kept.
+func ReprojectToWGS84(g *geojson.Geometry, srid int) (*geojson.Geometry, error) {
+	unproject, err := unprojection(srid)
+	if err != nil {
+		return nil, err
+	}
+	return mapCoordinates(g, func(p []float64) ([]float64, error) {
+		if len(p) < 2 {
+			return nil, fmt.Errorf("point must be at least 2d. got %d elements", len(p))
+		}
+		lon, lat := unproject(p[0], p[1])
+		if math.IsNaN(lon) || math.IsNaN(lat) {
+			return nil, fmt.Errorf("coordinate %v is outside of EPSG:%d", p, srid)
+		}
+		return append([]float64{lon, lat}, p[2:]...), nil
+	})
+}
+
+// unprojection finds the function converting coordinates of the SRID into longitude & latitude
+func unprojection(srid int) (func(x, y float64) (lon, lat float64), error) {
+	switch {
+	case srid == SRIDWGS84, srid == SRIDNAD83:
+		return func(x, y float64) (float64, float64) { return x, y }, nil
+	case srid == SRIDWebMercator, srid == 900913, srid == 3785, srid == 102100:
+		return webMercatorToWGS84, nil
+	case srid >= 32601 && srid <= 32660:
+		return utmToWGS84(srid-32600, false), nil
+	case srid >= 32701 && srid <= 32760:
+		return utmToWGS84(srid-32700, true), nil
+	case srid >= 26901 && srid <= 26923:
+		return utmToWGS84(srid-26900, false), nil
+	}
+	return nil, fmt.Errorf("unsupported SRID %d", srid)
+}
+
+func webMercatorToWGS84(x, y float64) (lon, lat float64) {
+	lon = x / wgs84SemiMajorAxis * 180 / math.Pi
+	lat = (2*math.Atan(math.Exp(y/wgs84SemiMajorAxis)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat
+}
+
+// utmToWGS84 returns the inverse transverse mercator projection of a UTM zone, following
+// Snyder's "Map Projections - A Working Manual"
+func utmToWGS84(zone int, south bool) func(x, y float64) (lon, lat float64) {
+	const k0 = 0.9996
+	a := wgs84SemiMajorAxis
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	centralMeridian := float64(zone-1)*6 - 180 + 3
+	return func(x, y float64) (float64, float64) {
+		x -= 500000
+		if south {
+			y -= 10000000
+		}
+		m := y / k0
+		mu := m / (a * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+		phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+			(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+			(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+			(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+		sinPhi1, cosPhi1, tanPhi1 := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+		c1 := ep2 * cosPhi1 * cosPhi1
+		t1 := tanPhi1 * tanPhi1
+		n1 := a / math.Sqrt(1-e2*sinPhi1*sinPhi1)
+		r1 := a * (1 - e2) / math.Pow(1-e2*sinPhi1*sinPhi1, 1.5)
+		d := x / (n1 * k0)
+		lat := phi1 - (n1*tanPhi1/r1)*(d*d/2-
+			(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+			(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+		lon := (d - (1+2*t1+c1)*math.Pow(d, 3)/6 +
+			(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120) / cosPhi1
+		return centralMeridian + lon*180/math.Pi, lat * 180 / math.Pi
+	}
+}
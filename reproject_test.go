@@ -0,0 +1,68 @@
+package spatially
+
+import (
+	"math"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestReprojectToWGS84(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		srid      int
+		x, y      float64
+		lon, lat  float64
+		tolerance float64
+	}{
+		{"wgs84", SRIDWGS84, -71.064156780428, 42.35862883483673, -71.064156780428, 42.35862883483673, 0},
+		{"nad83", SRIDNAD83, -71.064156780428, 42.35862883483673, -71.064156780428, 42.35862883483673, 0},
+		{"web mercator origin", SRIDWebMercator, 0, 0, 0, 0, 1e-12},
+		{"web mercator", SRIDWebMercator, webMercatorX(-71.064156780428), webMercatorY(42.35862883483673), -71.064156780428, 42.35862883483673, 1e-9},
+		{"google mercator", 900913, 20037508.342789244, 0, 180, 0, 1e-9},
+		{"utm 19N central meridian", 32619, 500000, 0, -69, 0, 1e-9},
+		{"utm 19S central meridian", 32719, 500000, 10000000, -69, 0, 1e-9},
+		{"utm 17N CN Tower", 32617, 630084, 4833438, -79.3871, 43.6426, 2e-4},
+		{"utm 17S mirrored CN Tower", 32717, 630084, 10000000 - 4833438, -79.3871, -43.6426, 2e-4},
+		{"nad83 utm 17N CN Tower", 26917, 630084, 4833438, -79.3871, 43.6426, 2e-4},
+	} {
+		g, err := ReprojectToWGS84(geojson.NewPointGeometry([]float64{test.x, test.y, 12}), test.srid)
+		if err != nil {
+			t.Error(test.name, err)
+			continue
+		}
+		lon, lat := g.Point[0], g.Point[1]
+		if math.Abs(lon-test.lon) > test.tolerance || math.Abs(lat-test.lat) > test.tolerance {
+			t.Errorf("%v: expected %v %v got %v %v", test.name, test.lon, test.lat, lon, lat)
+		}
+		if len(g.Point) != 3 || g.Point[2] != 12 {
+			t.Error(test.name, "the z value should be kept, got", g.Point)
+		}
+	}
+}
+
+func webMercatorX(lon float64) float64 {
+	return wgs84SemiMajorAxis * lon * math.Pi / 180
+}
+
+func webMercatorY(lat float64) float64 {
+	return wgs84SemiMajorAxis * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+}
+
+func TestReprojectToWGS84Geometry(t *testing.T) {
+	polygon := geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1000, 0}, {1000, 1000}, {0, 0}}})
+	g, err := ReprojectToWGS84(geojson.NewCollectionGeometry(polygon, geojson.NewMultiPointGeometry([]float64{0, 0})), SRIDWebMercator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := g.Geometries[0].Polygon[0]
+	if len(ring) != 4 || ring[0][0] != 0 || math.Abs(ring[1][0]-0.008983) > 1e-6 {
+		t.Error("Invalid reprojected ring", ring)
+	}
+	if polygon.Polygon[0][1][0] != 1000 {
+		t.Error("The original geometry should not be modified")
+	}
+	if _, err := ReprojectToWGS84(polygon, 2249); err == nil {
+		t.Error("Expected an error for an unsupported SRID")
+	}
+}
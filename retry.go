@@ -0,0 +1,140 @@
+package spatially
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail with a network error, a 5xx or a 429 response are
+// retried. Only idempotent requests are retried: GET, PUT and DELETE requests and the POST requests
+// that query data, like features, ATA and market size lookups. Creating layers and features is never
+// retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first. Values below 2
+	// disable retries.
+	MaxAttempts int
+	// MinBackoff is the wait before the first retry. It is doubled for each further retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts, including waits asked for with Retry-After
+	MaxBackoff time.Duration
+	// Jitter is the fraction of each wait, between 0 and 1, that is randomized
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries a request up to 3 times, waiting from 200ms up to 10s between attempts
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  200 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+	Jitter:      0.5,
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests. Clients don't retry by default.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+type idempotentKey struct{}
+
+// idempotent marks a POST request that only reads data so it can be retried
+func idempotent(request *http.Request) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), idempotentKey{}, true))
+}
+
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	marked, _ := request.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying
+func shouldRetry(request *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return request.Context().Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// backoff is the wait before the given retry, the first being 1
+func (p RetryPolicy) backoff(retry int, resp *http.Response) time.Duration {
+	wait := time.Duration(float64(p.MinBackoff) * math.Pow(2, float64(retry-1)))
+	if p.Jitter > 0 {
+		wait -= time.Duration(rand.Float64() * p.Jitter * float64(wait))
+	}
+	if after, ok := retryAfter(resp); ok {
+		wait = after
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// retryAfter reads the Retry-After header given either in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// rewind copies the request with a fresh body so it can be sent again
+func rewind(request *http.Request) (*http.Request, bool) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return request.Clone(request.Context()), true
+	}
+	if request.GetBody == nil {
+		return nil, false
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	next := request.Clone(request.Context())
+	next.Body = body
+	return next, true
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discard drains and closes the body of a response that won't be returned so its connection can be reused
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
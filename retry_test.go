@@ -0,0 +1,145 @@
+package spatially
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  20 * time.Millisecond,
+}
+
+func TestRetryIntermittentFailures(t *testing.T) {
+	var requests int32
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var request getFeaturesRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil || request.LayerID != "layer1" {
+			t.Error("Every attempt should send the request body, got", request, err)
+		}
+		json.NewEncoder(w).Encode(Features{})
+	}, WithRetryPolicy(testRetryPolicy))
+	defer server.Close()
+	features := NewFeatures()
+	if err := features.GetByLayer(sdb, "layer1"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Error("Expected 3 attempts, got", requests)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var requests int32
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithRetryPolicy(testRetryPolicy))
+	defer server.Close()
+	layers := NewLayers()
+	err := layers.Get(sdb)
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Error("Expected the last response error, got", err)
+	}
+	if requests != 3 {
+		t.Error("Expected 3 attempts, got", requests)
+	}
+}
+
+func TestRetryCreateFeatureNotRetried(t *testing.T) {
+	var requests int32
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}, WithRetryPolicy(testRetryPolicy))
+	defer server.Close()
+	feature := NewFeature()
+	geometry := geojson.NewPointGeometry([]float64{-71.06772422790527, 42.35848049347556})
+	if err := feature.Create(sdb, "layer1", geometry, nil); err == nil {
+		t.Error("Expected an error")
+	}
+	if requests != 1 {
+		t.Error("Creating a feature should not be retried, got", requests, "attempts")
+	}
+}
+
+func TestRetryClientErrorNotRetried(t *testing.T) {
+	var requests int32
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}, WithRetryPolicy(testRetryPolicy))
+	defer server.Close()
+	layers := NewLayers()
+	if err := layers.Get(sdb); err == nil {
+		t.Error("Expected an error")
+	}
+	if requests != 1 {
+		t.Error("A bad request should not be retried, got", requests, "attempts")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests int32
+	var first time.Time
+	sdb, server := newTestAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if wait := time.Since(first); wait < 20*time.Millisecond {
+			t.Error("Expected to wait for the capped Retry-After, waited", wait)
+		}
+		json.NewEncoder(w).Encode(Layers{})
+	}, WithRetryPolicy(testRetryPolicy))
+	defer server.Close()
+	layers := NewLayers()
+	if err := layers.Get(sdb); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Error("Expected 2 attempts, got", requests)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if _, ok := retryAfter(resp); ok {
+		t.Error("No Retry-After header was set")
+	}
+	resp.Header.Set("Retry-After", "120")
+	if wait, ok := retryAfter(resp); !ok || wait != 2*time.Minute {
+		t.Error("Invalid Retry-After seconds", wait)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if wait, ok := retryAfter(resp); !ok || wait < 59*time.Minute || wait > time.Hour {
+		t.Error("Invalid Retry-After date", wait)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for retry, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+		if wait := policy.backoff(retry+1, nil); wait != expected {
+			t.Error("Retry", retry+1, "expected backoff", expected, "got", wait)
+		}
+	}
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if wait := policy.backoff(2, nil); wait < 100*time.Millisecond || wait > 200*time.Millisecond {
+			t.Fatal("Jittered backoff out of range", wait)
+		}
+	}
+}
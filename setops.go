@@ -0,0 +1,165 @@
+package spatially
+
+import (
+	"fmt"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+// Union returns the polygons covering a or b, e.g. the trade areas of two stores merged
+func Union(a, b *geojson.Geometry) (*geojson.Geometry, error) {
+	return setOperation(func(inA, inB bool) bool { return inA || inB }, a, b)
+}
+
+// Intersection returns the polygons covering both a and b, e.g. where the trade areas of two stores overlap.
+// Polygons which only touch have no intersection.
+func Intersection(a, b *geojson.Geometry) (*geojson.Geometry, error) {
+	return setOperation(func(inA, inB bool) bool { return inA && inB }, a, b)
+}
+
+// Difference returns the polygons covering a but not b
+func Difference(a, b *geojson.Geometry) (*geojson.Geometry, error) {
+	return setOperation(func(inA, inB bool) bool { return inA && !inB }, a, b)
+}
+
+// SymDifference returns the polygons covering either a or b but not both
+func SymDifference(a, b *geojson.Geometry) (*geojson.Geometry, error) {
+	return setOperation(func(inA, inB bool) bool { return inA != inB }, a, b)
+}
+
+// UnionAll returns the polygons covering any of the geometries, e.g. the trade areas of a chain's stores
+func UnionAll(geometries ...*geojson.Geometry) (*geojson.Geometry, error) {
+	if len(geometries) == 0 {
+		return geojson.NewPolygonGeometry([][][]float64{}), nil
+	}
+	polygons, err := polygonal(geometries...)
+	if err != nil {
+		return nil, err
+	}
+	projection, originals := setProjection(polygons)
+	var planar []planarPolygon
+	for _, polygon := range polygons {
+		planar = append(planar, projection.forwardPolygon(polygon))
+	}
+	return unprojectExactly(projection, originals, unionPolygons(planar)), nil
+}
+
+// setOperation combines the polygons of a and b, keeping the regions for which keep is true. The polygons of
+// Polygon, MultiPolygon and GeometryCollection geometries are combined on a plane tangent to the WGS84
+// ellipsoid, where the straight edges of GeoJSON stay straight. The polygons of a geometry may overlap, as
+// the features of an ATA do, and a region is in the geometry when it's in any of them. Vertices are kept as
+// they are and new vertices are added where edges cross, without Z values. The result is a Polygon, or a
+// MultiPolygon when there are several polygons, and is an empty Polygon when there are none.
+func setOperation(keep func(inA, inB bool) bool, a, b *geojson.Geometry) (*geojson.Geometry, error) {
+	polygonsA, err := polygonal(a)
+	if err != nil {
+		return nil, err
+	}
+	polygonsB, err := polygonal(b)
+	if err != nil {
+		return nil, err
+	}
+	projection, originals := setProjection(append(append([][][][]float64{}, polygonsA...), polygonsB...))
+	var groups [2][]planarPolygon
+	for i, polygons := range [][][][][]float64{polygonsA, polygonsB} {
+		for _, polygon := range polygons {
+			groups[i] = append(groups[i], projection.forwardPolygon(polygon))
+		}
+	}
+	result := overlay(groups[:], func(inside []bool) bool { return keep(inside[0], inside[1]) })
+	return unprojectExactly(projection, originals, result), nil
+}
+
+// polygonal returns the polygons of geometries, which may only have polygons
+func polygonal(geometries ...*geojson.Geometry) ([][][][]float64, error) {
+	var polygons [][][][]float64
+	for _, g := range geometries {
+		if g == nil {
+			return nil, fmt.Errorf("nil geometry")
+		}
+		points, lines, p := flatten(g)
+		if len(points) > 0 || len(lines) > 0 {
+			return nil, fmt.Errorf("can only combine polygons, got points or linestrings in a %s", g.Type)
+		}
+		for _, polygon := range p {
+			if len(polygon) > 0 {
+				polygons = append(polygons, polygon)
+			}
+		}
+	}
+	return polygons, nil
+}
+
+// setProjection returns the projection centered on polygons and the coordinates of their vertices by
+// projected vertex
+func setProjection(polygons [][][][]float64) (localProjection, map[vec][]float64) {
+	bbox := BBox(geojson.NewMultiPolygonGeometry(polygons...))
+	if bbox == nil {
+		bbox = []float64{0, 0, 0, 0}
+	}
+	projection := newLocalProjection(bbox)
+	originals := map[vec][]float64{}
+	for _, polygon := range polygons {
+		for _, r := range polygon {
+			for _, p := range r {
+				originals[projection.forward(p)] = p[:2]
+			}
+		}
+	}
+	return projection, originals
+}
+
+// unprojectExactly returns planar polygons as a geometry, see unprojectPolygons
+func unprojectExactly(projection localProjection, originals map[vec][]float64, polygons []planarPolygon) *geojson.Geometry {
+	unprojected := unprojectPolygons(projection, originals, polygons)
+	switch len(unprojected) {
+	case 0:
+		return geojson.NewPolygonGeometry([][][]float64{})
+	case 1:
+		return geojson.NewPolygonGeometry(unprojected[0])
+	}
+	return geojson.NewMultiPolygonGeometry(unprojected...)
+}
+
+// unprojectPolygons returns the coordinates of planar polygons, the original coordinates of the vertices
+// which were projected and the new vertices unprojected
+func unprojectPolygons(projection localProjection, originals map[vec][]float64, polygons []planarPolygon) [][][][]float64 {
+	unprojected := make([][][][]float64, len(polygons))
+	for i, polygon := range polygons {
+		for _, r := range polygon {
+			var coordinates [][]float64
+			for _, v := range r {
+				if original, ok := originals[v]; ok {
+					coordinates = append(coordinates, original)
+				} else {
+					coordinates = append(coordinates, projection.inverse(v))
+				}
+			}
+			unprojected[i] = append(unprojected[i], coordinates)
+		}
+	}
+	return unprojected
+}
+
+// Geometry returns the union of the polygons of the ATA's features, which can be combined with the geometry
+// of another ATA, e.g. Intersection(ata1Geometry, ata2Geometry) for the overlap of two trade areas
+func (a *ATA) Geometry() (*geojson.Geometry, error) {
+	fc, err := a.GeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	var polygons []*geojson.Geometry
+	for _, feature := range fc.Features {
+		if feature.Geometry == nil {
+			continue
+		}
+		_, _, p := flatten(feature.Geometry)
+		polygons = append(polygons, geojson.NewMultiPolygonGeometry(p...))
+	}
+	g, err := UnionAll(polygons...)
+	if err != nil {
+		return nil, errors.Wrap(err, "ata union")
+	}
+	return g, nil
+}
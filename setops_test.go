@@ -0,0 +1,179 @@
+package spatially
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Spatially/go-geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func degreeArea(g *geojson.Geometry) float64 {
+	_, _, polygons := flatten(g)
+	area := 0.0
+	for _, polygon := range polygons {
+		for _, r := range polygon {
+			var points ring
+			for _, p := range r {
+				points = append(points, vec{p[0], p[1]})
+			}
+			area += points.signedArea()
+		}
+	}
+	return area
+}
+
+func TestSetOperations(t *testing.T) {
+	for _, test := range []struct {
+		a, b                                           string
+		union, intersection, difference, symDifference float64
+		parts                                          [4]int
+	}{
+		// overlapping squares
+		{
+			"POLYGON((0 0,0.02 0,0.02 0.02,0 0.02,0 0))", "POLYGON((0.01 0.01,0.03 0.01,0.03 0.03,0.01 0.03,0.01 0.01))",
+			7e-4, 1e-4, 3e-4, 6e-4, [4]int{1, 1, 1, 2},
+		},
+		// squares sharing an edge
+		{
+			"POLYGON((0 0,0.01 0,0.01 0.01,0 0.01,0 0))", "POLYGON((0.01 0,0.02 0,0.02 0.01,0.01 0.01,0.01 0))",
+			2e-4, 0, 1e-4, 2e-4, [4]int{1, 0, 1, 1},
+		},
+		// squares touching at a corner
+		{
+			"POLYGON((0 0,0.01 0,0.01 0.01,0 0.01,0 0))", "POLYGON((0.01 0.01,0.02 0.01,0.02 0.02,0.01 0.02,0.01 0.01))",
+			2e-4, 0, 1e-4, 2e-4, [4]int{2, 0, 1, 2},
+		},
+		// identical squares
+		{
+			"POLYGON((0 0,0.01 0,0.01 0.01,0 0.01,0 0))", "POLYGON((0 0,0.01 0,0.01 0.01,0 0.01,0 0))",
+			1e-4, 1e-4, 0, 0, [4]int{1, 1, 0, 0},
+		},
+		// a square in the hole of another
+		{
+			"POLYGON((0 0,0.03 0,0.03 0.03,0 0.03,0 0),(0.01 0.01,0.01 0.02,0.02 0.02,0.02 0.01,0.01 0.01))",
+			"POLYGON((0.005 0.005,0.025 0.005,0.025 0.025,0.005 0.025,0.005 0.005))",
+			9e-4, 3e-4, 5e-4, 6e-4, [4]int{1, 1, 1, 2},
+		},
+		// overlapping polygons of a MultiPolygon are merged
+		{
+			"MULTIPOLYGON(((0 0,0.02 0,0.02 0.02,0 0.02,0 0)),((0.01 0,0.03 0,0.03 0.02,0.01 0.02,0.01 0)))",
+			"POLYGON EMPTY",
+			6e-4, 0, 6e-4, 6e-4, [4]int{1, 0, 1, 1},
+		},
+	} {
+		a, b := mustWKT(t, test.a), mustWKT(t, test.b)
+		for i, operation := range []func(a, b *geojson.Geometry) (*geojson.Geometry, error){Union, Intersection, Difference, SymDifference} {
+			expected := []float64{test.union, test.intersection, test.difference, test.symDifference}[i]
+			result, err := operation(a, b)
+			if err != nil {
+				t.Error(test.a, test.b, err)
+				continue
+			}
+			_, _, polygons := flatten(result)
+			if math.Abs(degreeArea(result)-expected) > 1e-9 || len(polygons) != test.parts[i] {
+				wkt, _ := GeometryToWKT(result)
+				t.Errorf("Expected %d polygons of area %g for operation %d of %s and %s, got %s", test.parts[i], expected, i, test.a, test.b, wkt)
+			}
+			if err := Validate(result); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestSetOperationVertices(t *testing.T) {
+	a := mustWKT(t, "POLYGON((-71.07 42.35,-71.05 42.35,-71.05 42.37,-71.07 42.37,-71.07 42.35))")
+	b := mustWKT(t, "POLYGON((-71.06 42.36,-71.04 42.36,-71.04 42.38,-71.06 42.38,-71.06 42.36))")
+	union, err := Union(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the vertices of a and b are kept as they are, and the crossings are close to the corners they make
+	exact := map[[2]float64]bool{}
+	for _, g := range []*geojson.Geometry{a, b} {
+		for _, p := range g.Polygon[0] {
+			exact[[2]float64{p[0], p[1]}] = true
+		}
+	}
+	kept, crossings := 0, 0
+	for _, p := range union.Polygon[0] {
+		if exact[[2]float64{p[0], p[1]}] {
+			kept++
+		} else if math.Abs(p[0]+71.05) < 1e-9 && math.Abs(p[1]-42.36) < 1e-6 || math.Abs(p[0]+71.06) < 1e-9 && math.Abs(p[1]-42.37) < 1e-6 {
+			crossings++
+		}
+	}
+	if kept != 7 || crossings != 2 {
+		wkt, _ := GeometryToWKT(union)
+		t.Errorf("Expected the 6 outer corners, closing the ring, and 2 crossings, got %s", wkt)
+	}
+}
+
+func TestUnionAll(t *testing.T) {
+	var geometries []*geojson.Geometry
+	for i := 0; i < 5; i++ {
+		x := 0.01 * float64(i)
+		geometries = append(geometries, geojson.NewPolygonGeometry([][][]float64{{{x, 0}, {x + 0.02, 0}, {x + 0.02, 0.01}, {x, 0.01}, {x, 0}}}))
+	}
+	union, err := UnionAll(geometries...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if union.Type != geojson.GeometryPolygon || math.Abs(degreeArea(union)-6e-4) > 1e-9 {
+		wkt, _ := GeometryToWKT(union)
+		t.Error("Expected a single polygon, got", wkt)
+	}
+	empty, err := UnionAll()
+	if err != nil || empty.Type != geojson.GeometryPolygon || len(empty.Polygon) != 0 {
+		t.Errorf("Expected an empty polygon, got %+v %v", empty, err)
+	}
+}
+
+func TestSetOperationErrors(t *testing.T) {
+	polygon := mustWKT(t, "POLYGON((0 0,1 0,1 1,0 1,0 0))")
+	if _, err := Union(polygon, geojson.NewPointGeometry([]float64{0, 0})); err == nil {
+		t.Error("Expected an error for a point")
+	}
+	if _, err := Difference(mustWKT(t, "GEOMETRYCOLLECTION(LINESTRING(0 0,1 1))"), polygon); err == nil {
+		t.Error("Expected an error for a linestring")
+	}
+	if _, err := Intersection(polygon, nil); err == nil {
+		t.Error("Expected an error for a nil geometry")
+	}
+	if _, err := UnionAll(polygon, nil); err == nil {
+		t.Error("Expected an error for a nil geometry")
+	}
+}
+
+func TestATAGeometry(t *testing.T) {
+	fc := geojson.NewFeatureCollection()
+	fc.AddFeature(geojson.NewFeature(mustWKT(t, "POLYGON((0 0,0.02 0,0.02 0.02,0 0.02,0 0))")))
+	fc.AddFeature(geojson.NewFeature(mustWKT(t, "MULTIPOLYGON(((0.01 0.01,0.03 0.01,0.03 0.03,0.01 0.03,0.01 0.01)))")))
+	j, err := json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var featureCollection geometry.FeatureCollection
+	if err := json.Unmarshal(j, &featureCollection); err != nil {
+		t.Fatal(err)
+	}
+	g, err := (&ATA{&featureCollection}).Geometry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Type != geojson.GeometryPolygon || math.Abs(degreeArea(g)-7e-4) > 1e-9 {
+		wkt, _ := GeometryToWKT(g)
+		t.Error("Expected the features merged, got", wkt)
+	}
+	other := mustWKT(t, "POLYGON((0.025 0.025,0.04 0.025,0.04 0.04,0.025 0.04,0.025 0.025))")
+	overlap, err := Intersection(g, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(degreeArea(overlap)-0.25e-4) > 1e-9 {
+		wkt, _ := GeometryToWKT(overlap)
+		t.Error("Unexpected overlap", wkt)
+	}
+}
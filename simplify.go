@@ -0,0 +1,534 @@
+package spatially
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Spatially/go-geometry"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+// SimplifyAlgorithm selects the vertices Simplify removes
+type SimplifyAlgorithm int
+
+const (
+	// DouglasPeucker keeps the vertices farthest from the simplified lines, which follows the outline of
+	// shapes closely
+	DouglasPeucker SimplifyAlgorithm = iota
+	// VisvalingamWhyatt removes the vertices making the smallest triangles with their neighbors, which
+	// removes small details evenly and keeps smoother shapes
+	VisvalingamWhyatt
+)
+
+// SimplifyOptions configure Simplify
+type SimplifyOptions struct {
+	Algorithm SimplifyAlgorithm
+	// Tolerance in meters. DouglasPeucker keeps the vertices farther than Tolerance from the simplified
+	// lines and VisvalingamWhyatt removes the vertices whose triangle with their neighbors has an area up
+	// to Tolerance² square meters. Collinear and repeated vertices are removed even when it's 0.
+	Tolerance float64
+	// MaxVertices, when positive, keeps simplifying past the tolerance until the geometry has at most that
+	// many vertices, the closing vertices of rings and points included
+	MaxVertices int
+	// PreserveTopology keeps the vertices whose removal would make edges of the geometry cross or move a
+	// vertex to the other side of an edge, so valid polygons stay valid and holes stay in their shells.
+	// MaxVertices can't always be reached then.
+	PreserveTopology bool
+}
+
+// Simplify returns a copy of g with fewer vertices. The ends of linestrings and the first vertex of rings
+// are kept, linestrings keep at least 2 vertices and rings at least 4, so no part of g disappears. Points
+// are kept as they are. Distances and areas are measured on a plane tangent to the WGS84 ellipsoid at the
+// center of g, like Buffer.
+func Simplify(g *geojson.Geometry, options *SimplifyOptions) (*geojson.Geometry, error) {
+	if options == nil {
+		options = &SimplifyOptions{}
+	}
+	if options.Tolerance < 0 || math.IsNaN(options.Tolerance) {
+		return nil, fmt.Errorf("simplify tolerance must not be negative, got %v", options.Tolerance)
+	}
+	if options.Algorithm != DouglasPeucker && options.Algorithm != VisvalingamWhyatt {
+		return nil, fmt.Errorf("unknown simplify algorithm %d", options.Algorithm)
+	}
+	c, err := mapCoordinates(g, func(p []float64) ([]float64, error) {
+		if len(p) < 2 {
+			return nil, fmt.Errorf("point must be at least 2d. got %d elements", len(p))
+		}
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	bbox := BBox(c)
+	if bbox == nil {
+		return c, nil
+	}
+	s := &simplifier{projection: newLocalProjection(bbox), topology: options.PreserveTopology}
+	s.collect(c)
+	if s.topology {
+		s.newIndex()
+	}
+	if options.Algorithm == DouglasPeucker {
+		s.douglasPeucker(options.Tolerance, options.MaxVertices)
+	} else {
+		s.visvalingamWhyatt(options.Tolerance*options.Tolerance, options.MaxVertices)
+	}
+	s.apply()
+	return c, nil
+}
+
+// Simplify returns the ATA with the geometries of its features simplified together, see Simplify. The
+// vertices of all the features count towards MaxVertices and topology is preserved between features.
+func (a *ATA) Simplify(options *SimplifyOptions) (*ATA, error) {
+	fc, err := a.GeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	collection := geojson.NewCollectionGeometry()
+	for _, feature := range fc.Features {
+		if feature.Geometry == nil {
+			// an empty collection stands in for a missing geometry, which stays missing
+			collection.Geometries = append(collection.Geometries, geojson.NewCollectionGeometry())
+			continue
+		}
+		collection.Geometries = append(collection.Geometries, feature.Geometry)
+	}
+	simplified, err := Simplify(collection, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "ata simplify")
+	}
+	for i, feature := range fc.Features {
+		if feature.Geometry != nil {
+			feature.Geometry = simplified.Geometries[i]
+		}
+	}
+	j, err := json.Marshal(fc)
+	if err != nil {
+		return nil, errors.Wrap(err, "ata json marshal")
+	}
+	var featureCollection geometry.FeatureCollection
+	if err := json.Unmarshal(j, &featureCollection); err != nil {
+		return nil, errors.Wrap(err, "ata json unmarshal")
+	}
+	return &ATA{&featureCollection}, nil
+}
+
+// simplifyLine is a linestring or ring of the geometry being simplified
+type simplifyLine struct {
+	// slot is where the line is in the geometry, replaced by the kept vertices once simplified
+	slot   *[][]float64
+	points []vec
+	kept   []bool
+	count  int
+	ring   bool
+	// fixed lines are too short to simplify
+	fixed bool
+	// segment holds the id of the segment starting at each kept vertex
+	segment []int
+	// version of the area of each vertex in the queue of VisvalingamWhyatt
+	version []int
+}
+
+// simplifySegment joins two kept vertices of a line, replacing the vertices between them
+type simplifySegment struct {
+	line, from, to int
+	dead           bool
+}
+
+type simplifier struct {
+	projection localProjection
+	topology   bool
+	lines      []*simplifyLine
+	// count is the number of kept vertices of the geometry
+	count    int
+	segments []simplifySegment
+	index    *gridIndex
+	// indexRegions indexes segments by the bounds of the vertices they replace rather than their own
+	indexRegions bool
+}
+
+// collect finds the lines of g
+func (s *simplifier) collect(g *geojson.Geometry) {
+	switch g.Type {
+	case geojson.GeometryPoint:
+		if len(g.Point) > 0 {
+			s.count++
+		}
+	case geojson.GeometryMultiPoint:
+		s.count += len(g.MultiPoint)
+	case geojson.GeometryLineString:
+		s.add(&g.LineString, false)
+	case geojson.GeometryMultiLineString:
+		for i := range g.MultiLineString {
+			s.add(&g.MultiLineString[i], false)
+		}
+	case geojson.GeometryPolygon:
+		for i := range g.Polygon {
+			s.add(&g.Polygon[i], true)
+		}
+	case geojson.GeometryMultiPolygon:
+		for _, polygon := range g.MultiPolygon {
+			for i := range polygon {
+				s.add(&polygon[i], true)
+			}
+		}
+	case geojson.GeometryCollection:
+		for _, geometry := range g.Geometries {
+			s.collect(geometry)
+		}
+	}
+}
+
+func (s *simplifier) add(slot *[][]float64, ring bool) {
+	if len(*slot) == 0 {
+		return
+	}
+	points := s.projection.forwardLine(*slot)
+	// a ring which isn't closed is simplified like a linestring, keeping both of its ends
+	ring = ring && points[0] == points[len(points)-1]
+	l := &simplifyLine{
+		slot:    slot,
+		points:  points,
+		kept:    make([]bool, len(points)),
+		ring:    ring,
+		fixed:   len(points) <= 2 || ring && len(points) <= 4,
+		segment: make([]int, len(points)),
+		version: make([]int, len(points)),
+	}
+	s.lines = append(s.lines, l)
+	if l.fixed {
+		for i := range l.kept {
+			s.keep(l, i)
+		}
+	}
+}
+
+func (s *simplifier) keep(l *simplifyLine, i int) {
+	if !l.kept[i] {
+		l.kept[i] = true
+		l.count++
+		s.count++
+	}
+}
+
+func (s *simplifier) drop(l *simplifyLine, i int) {
+	l.kept[i] = false
+	l.count--
+	s.count--
+}
+
+// newIndex creates the grid index of segments, with about as many cells as vertices
+func (s *simplifier) newIndex() {
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	n := 0
+	for _, l := range s.lines {
+		b := bounds(l.points)
+		bbox[0], bbox[1] = math.Min(bbox[0], b[0]), math.Min(bbox[1], b[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], b[2]), math.Max(bbox[3], b[3])
+		n += len(l.points)
+	}
+	size := math.Max(bbox[2]-bbox[0], bbox[3]-bbox[1]) / math.Sqrt(float64(n))
+	if !(size > 0) {
+		size = 1
+	}
+	s.index = newGridIndex(size)
+}
+
+// link adds the segment between two kept vertices of a line, indexed when preserving topology
+func (s *simplifier) link(line, from, to int) int {
+	id := len(s.segments)
+	s.segments = append(s.segments, simplifySegment{line: line, from: from, to: to})
+	points := s.lines[line].points
+	s.lines[line].segment[from] = id
+	if !s.topology {
+		return id
+	}
+	if s.indexRegions {
+		s.index.insert(id, bounds(points[from:to+1]))
+	} else {
+		s.index.insert(id, bounds([]vec{points[from], points[to]}))
+	}
+	return id
+}
+
+// linkKept adds the segments between the kept vertices of every line
+func (s *simplifier) linkKept() {
+	for li, l := range s.lines {
+		from := -1
+		for i, kept := range l.kept {
+			if !kept {
+				continue
+			}
+			if from >= 0 {
+				s.link(li, from, i)
+			}
+			from = i
+		}
+	}
+}
+
+func bounds(points []vec) [4]float64 {
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, v := range points {
+		bbox[0], bbox[1] = math.Min(bbox[0], v[0]), math.Min(bbox[1], v[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], v[0]), math.Max(bbox[3], v[1])
+	}
+	return bbox
+}
+
+// acceptable reports whether the vertices of a line between from and to can be replaced by a segment
+// without changing the topology of the geometry: the segment mustn't meet another segment other than at
+// their ends, and no other vertex may be in the region between the segment and the vertices it replaces
+func (s *simplifier) acceptable(line, from, to int, region ring) bool {
+	p, q := s.lines[line].points[from], s.lines[line].points[to]
+	replaced := func(t simplifySegment) bool {
+		return t.dead || t.line == line && t.from >= from && t.to <= to
+	}
+	ok := true
+	s.index.query(bounds([]vec{p, q}), func(id int) {
+		t := s.segments[id]
+		if ok && !replaced(t) && segmentsConflict(p, q, s.lines[t.line].points[t.from], s.lines[t.line].points[t.to]) {
+			ok = false
+		}
+	})
+	s.index.query(bounds(region), func(id int) {
+		t := s.segments[id]
+		if !ok || replaced(t) {
+			return
+		}
+		for _, v := range []vec{s.lines[t.line].points[t.from], s.lines[t.line].points[t.to]} {
+			if v != p && v != q && region.contains(v) {
+				ok = false
+			}
+		}
+	})
+	return ok
+}
+
+// segmentsConflict reports whether the segments p1 p2 and q1 q2 have a point in common other than an end
+// they share
+func segmentsConflict(p1, p2, q1, q2 vec) bool {
+	o1, o2 := cross(p2.sub(p1), q1.sub(p1)), cross(p2.sub(p1), q2.sub(p1))
+	o3, o4 := cross(q2.sub(q1), p1.sub(q1)), cross(q2.sub(q1), p2.sub(q1))
+	for _, ends := range [][4]vec{{p1, p2, q1, q2}, {p2, p1, q1, q2}, {p1, p2, q2, q1}, {p2, p1, q2, q1}} {
+		if ends[0] != ends[2] {
+			continue
+		}
+		// segments sharing an end conflict when they overlap
+		x, y := ends[1].sub(ends[0]), ends[3].sub(ends[0])
+		return o1 == 0 && o2 == 0 && x[0]*y[0]+x[1]*y[1] > 0 || ends[1] == ends[3]
+	}
+	if (o1 > 0 && o2 < 0 || o1 < 0 && o2 > 0) && (o3 > 0 && o4 < 0 || o3 < 0 && o4 > 0) {
+		return true
+	}
+	between := func(v, a, b vec) bool {
+		return math.Min(a[0], b[0]) <= v[0] && v[0] <= math.Max(a[0], b[0]) &&
+			math.Min(a[1], b[1]) <= v[1] && v[1] <= math.Max(a[1], b[1])
+	}
+	return o1 == 0 && between(q1, p1, p2) || o2 == 0 && between(q2, p1, p2) ||
+		o3 == 0 && between(p1, q1, q2) || o4 == 0 && between(p2, q1, q2)
+}
+
+// farthest returns the vertex of a line between from and to farthest from the segment joining them
+func (l *simplifyLine) farthest(from, to int) (int, float64) {
+	k, d := -1, -1.0
+	for i := from + 1; i < to; i++ {
+		if di := distanceToEdge(l.points[i], l.points[from], l.points[to]); di > d {
+			k, d = i, di
+		}
+	}
+	return k, d
+}
+
+// region returns the ring of the vertices of a line from one vertex to another and back
+func (l *simplifyLine) region(from, to int) ring {
+	return append(append(ring{}, l.points[from:to+1]...), l.points[from])
+}
+
+// douglasPeucker starts from the ends of every line and keeps the vertex farthest from the simplified
+// lines until all the others are within the tolerance, or there are as many vertices as wanted. Preserving
+// topology then keeps more vertices around the segments which aren't acceptable.
+func (s *simplifier) douglasPeucker(tolerance float64, maxVertices int) {
+	queue := &simplifyQueue{}
+	push := func(line, from, to int) {
+		if k, d := s.lines[line].farthest(from, to); k >= 0 {
+			heap.Push(queue, simplifyItem{line: line, from: from, to: to, vertex: k, key: d})
+		}
+	}
+	for li, l := range s.lines {
+		if l.fixed {
+			continue
+		}
+		last := len(l.points) - 1
+		s.keep(l, 0)
+		s.keep(l, last)
+		if !l.ring {
+			push(li, 0, last)
+			continue
+		}
+		// the segment closing a ring is a point, its farthest vertex and then the farthest from either
+		// half make the smallest ring
+		k, _ := l.farthest(0, last)
+		s.keep(l, k)
+		k1, d1 := l.farthest(0, k)
+		k2, d2 := l.farthest(k, last)
+		if d1 >= d2 {
+			s.keep(l, k1)
+			push(li, 0, k1)
+			push(li, k1, k)
+			push(li, k, last)
+		} else {
+			s.keep(l, k2)
+			push(li, 0, k)
+			push(li, k, k2)
+			push(li, k2, last)
+		}
+	}
+	for queue.Len() > 0 {
+		top := (*queue)[0]
+		if top.key <= tolerance || maxVertices > 0 && s.count >= maxVertices {
+			break
+		}
+		heap.Pop(queue)
+		s.keep(s.lines[top.line], top.vertex)
+		push(top.line, top.from, top.vertex)
+		push(top.line, top.vertex, top.to)
+	}
+	if !s.topology {
+		return
+	}
+
+	// the regions of segments are indexed to find the segments around the vertices kept next
+	s.indexRegions = true
+	s.linkKept()
+	queued := map[int]bool{}
+	var work []int
+	enqueue := func(id int) {
+		if t := s.segments[id]; !t.dead && t.to-t.from > 1 && !queued[id] {
+			queued[id] = true
+			work = append(work, id)
+		}
+	}
+	for id := range s.segments {
+		enqueue(id)
+	}
+	for len(work) > 0 {
+		id := work[len(work)-1]
+		work = work[:len(work)-1]
+		delete(queued, id)
+		t := s.segments[id]
+		l := s.lines[t.line]
+		if t.dead || s.acceptable(t.line, t.from, t.to, l.region(t.from, t.to)) {
+			continue
+		}
+		k, _ := l.farthest(t.from, t.to)
+		s.segments[id].dead = true
+		s.keep(l, k)
+		enqueue(s.link(t.line, t.from, k))
+		enqueue(s.link(t.line, k, t.to))
+		// the new segments may cross segments accepted before, and the new vertex be in their regions
+		s.index.query(bounds(l.points[t.from:t.to+1]), enqueue)
+	}
+}
+
+// visvalingamWhyatt starts from all the vertices and removes the vertex making the smallest triangle with
+// its neighbors until the others make larger triangles than the tolerance, and there are as few vertices as
+// wanted. A vertex whose removal isn't acceptable when preserving topology is skipped until a neighbor is
+// removed.
+func (s *simplifier) visvalingamWhyatt(tolerance float64, maxVertices int) {
+	queue := &simplifyQueue{}
+	var prev, next [][]int
+	push := func(line, b int, minimum float64) {
+		l := s.lines[line]
+		a, c := prev[line][b], next[line][b]
+		area := math.Abs(cross(l.points[a].sub(l.points[b]), l.points[c].sub(l.points[b]))) / 2
+		l.version[b]++
+		// the area of a vertex is at least that of the vertices removed before it, so vertices are removed in
+		// order of area even once their neighbors change
+		heap.Push(queue, simplifyItem{line: line, vertex: b, key: -math.Max(area, minimum), version: l.version[b]})
+	}
+	for li, l := range s.lines {
+		prev, next = append(prev, make([]int, len(l.points))), append(next, make([]int, len(l.points)))
+		for i := range l.points {
+			prev[li][i], next[li][i] = i-1, i+1
+		}
+		if l.fixed {
+			continue
+		}
+		for i := range l.points {
+			s.keep(l, i)
+		}
+		for i := 1; i+1 < len(l.points); i++ {
+			push(li, i, 0)
+		}
+	}
+	if s.topology {
+		s.linkKept()
+	}
+	for queue.Len() > 0 {
+		top := heap.Pop(queue).(simplifyItem)
+		l := s.lines[top.line]
+		if top.version != l.version[top.vertex] || !l.kept[top.vertex] {
+			continue
+		}
+		if -top.key > tolerance && (maxVertices <= 0 || s.count <= maxVertices) {
+			break
+		}
+		if l.ring && l.count <= 4 {
+			continue
+		}
+		a, b, c := prev[top.line][top.vertex], top.vertex, next[top.line][top.vertex]
+		if s.topology {
+			if !s.acceptable(top.line, a, c, ring{l.points[a], l.points[b], l.points[c], l.points[a]}) {
+				continue
+			}
+			s.segments[l.segment[a]].dead = true
+			s.segments[l.segment[b]].dead = true
+			s.link(top.line, a, c)
+		}
+		s.drop(l, b)
+		next[top.line][a], prev[top.line][c] = c, a
+		if a > 0 {
+			push(top.line, a, -top.key)
+		}
+		if c < len(l.points)-1 {
+			push(top.line, c, -top.key)
+		}
+	}
+}
+
+// apply replaces the lines of the geometry with their kept vertices
+func (s *simplifier) apply() {
+	for _, l := range s.lines {
+		line := make([][]float64, 0, l.count)
+		for i, p := range *l.slot {
+			if l.kept[i] {
+				line = append(line, p)
+			}
+		}
+		*l.slot = line
+	}
+}
+
+type simplifyItem struct {
+	line, from, to, vertex int
+	key                    float64
+	version                int
+}
+
+// simplifyQueue is a heap of the items with the largest key first
+type simplifyQueue []simplifyItem
+
+func (q simplifyQueue) Len() int            { return len(q) }
+func (q simplifyQueue) Less(i, j int) bool  { return q[i].key > q[j].key }
+func (q simplifyQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *simplifyQueue) Push(x interface{}) { *q = append(*q, x.(simplifyItem)) }
+func (q *simplifyQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,199 @@
+package spatially
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Spatially/go-geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func vertexCount(g *geojson.Geometry) int {
+	points, lines, polygons := flatten(g)
+	n := len(points)
+	for _, line := range lines {
+		n += len(line)
+	}
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			n += len(ring)
+		}
+	}
+	return n
+}
+
+func TestSimplify(t *testing.T) {
+	for _, test := range []struct {
+		wkt      string
+		options  SimplifyOptions
+		expected string
+	}{
+		// the 1m bump goes, the 110m turn stays
+		{
+			"LINESTRING(0 0,0.001 0.00001,0.002 0,0.003 0.001,0.004 0)",
+			SimplifyOptions{Algorithm: DouglasPeucker, Tolerance: 10},
+			"LINESTRING(0 0,0.002 0,0.003 0.001,0.004 0)",
+		},
+		{
+			"LINESTRING(0 0,0.001 0.00001,0.002 0,0.003 0.001,0.004 0)",
+			SimplifyOptions{Algorithm: VisvalingamWhyatt, Tolerance: 20},
+			"LINESTRING(0 0,0.002 0,0.003 0.001,0.004 0)",
+		},
+		// collinear and repeated vertices go without a tolerance
+		{
+			"LINESTRING(0 0,1 0,1 0,2 0,2 1)",
+			SimplifyOptions{Algorithm: DouglasPeucker},
+			"LINESTRING(0 0,2 0,2 1)",
+		},
+		{
+			"LINESTRING(0 0,1 0,1 0,2 0,2 1)",
+			SimplifyOptions{Algorithm: VisvalingamWhyatt},
+			"LINESTRING(0 0,2 0,2 1)",
+		},
+		// rings keep 4 vertices and linestrings their ends
+		{
+			"POLYGON((0 0,0.5 0,1 0,1 1,0 1,0 0))",
+			SimplifyOptions{Algorithm: DouglasPeucker, Tolerance: 1e6},
+			"POLYGON((0 0,1 0,1 1,0 0))",
+		},
+		{
+			"POLYGON((0 0,0.5 0,1 0,1 1,0 1,0 0))",
+			SimplifyOptions{Algorithm: VisvalingamWhyatt, Tolerance: 1e6},
+			"POLYGON((0 0,1 1,0 1,0 0))",
+		},
+		{
+			"GEOMETRYCOLLECTION(POINT(5 5),LINESTRING(0 0,1 1,2 0),POLYGON((0 0,1 0,1 1,0 0)))",
+			SimplifyOptions{Tolerance: 1e6},
+			"GEOMETRYCOLLECTION(POINT(5 5),LINESTRING(0 0,2 0),POLYGON((0 0,1 0,1 1,0 0)))",
+		},
+		{"POLYGON EMPTY", SimplifyOptions{Tolerance: 10}, "POLYGON EMPTY"},
+	} {
+		g := mustWKT(t, test.wkt)
+		simplified, err := Simplify(g, &test.options)
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		if wkt, _ := GeometryToWKT(simplified); wkt != test.expected {
+			t.Errorf("Expected %s simplified to %s, got %s", test.wkt, test.expected, wkt)
+		}
+		if wkt, _ := GeometryToWKT(g); wkt != test.wkt {
+			t.Error("Expected the geometry to be left as it was, got", wkt)
+		}
+	}
+}
+
+func TestSimplifyMaxVertices(t *testing.T) {
+	circle := geojson.NewPolygonGeometry([][][]float64{geodesicCircle([]float64{-71.06, 42.35}, 1000, 100)})
+	for _, algorithm := range []SimplifyAlgorithm{DouglasPeucker, VisvalingamWhyatt} {
+		for _, topology := range []bool{false, true} {
+			simplified, err := Simplify(circle, &SimplifyOptions{Algorithm: algorithm, MaxVertices: 10, PreserveTopology: topology})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := vertexCount(simplified); n != 10 {
+				t.Errorf("Expected 10 vertices, got %d", n)
+			}
+			// the vertices are spread around the circle, not bunched together
+			if area := Area(simplified); area < 0.8*Area(circle) {
+				t.Errorf("Expected most of the circle to be covered, got %f m² of %f", area, Area(circle))
+			}
+		}
+	}
+	// the tolerance keeps fewer vertices than the maximum
+	simplified, err := Simplify(circle, &SimplifyOptions{Tolerance: 1000, MaxVertices: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := vertexCount(simplified); n != 4 {
+		t.Errorf("Expected 4 vertices, got %d", n)
+	}
+}
+
+func TestSimplifyPreserveTopology(t *testing.T) {
+	// the shell bulges 55m up around a hole, which simplifying to 200m would leave outside of the shell, and
+	// is dented 55m around a line, which would end up inside of it
+	polygon := mustWKT(t, "POLYGON((0 0,0.0045 0,0.005 0.0005,0.0055 0,0.01 0,0.01 0.01,0.0055 0.01,0.005 0.0105,0.0045 0.01,0 0.01,0 0),"+
+		"(0.0049 0.0101,0.0049 0.0103,0.0051 0.0103,0.0051 0.0101,0.0049 0.0101))")
+	line := mustWKT(t, "LINESTRING(0.0049 0.0001,0.0051 0.0001)")
+	for _, algorithm := range []SimplifyAlgorithm{DouglasPeucker, VisvalingamWhyatt} {
+		for _, topology := range []bool{false, true} {
+			collection := geojson.NewCollectionGeometry(polygon, line)
+			simplified, err := Simplify(collection, &SimplifyOptions{Algorithm: algorithm, Tolerance: 200, PreserveTopology: topology})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := simplified.Geometries[0]
+			shell := geojson.NewPolygonGeometry(p.Polygon[:1])
+			hole := geojson.NewPolygonGeometry(p.Polygon[1:])
+			if valid := Within(hole, shell) && !Intersects(shell, simplified.Geometries[1]); valid != topology {
+				t.Errorf("Expected the topology of algorithm %d to be preserved: %t, got %t", algorithm, topology, valid)
+			}
+		}
+	}
+}
+
+func TestSimplifyErrors(t *testing.T) {
+	g := mustWKT(t, "LINESTRING(0 0,1 1,2 0)")
+	if _, err := Simplify(g, &SimplifyOptions{Tolerance: -1}); err == nil {
+		t.Error("Expected an error for a negative tolerance")
+	}
+	if _, err := Simplify(g, &SimplifyOptions{Algorithm: SimplifyAlgorithm(7)}); err == nil {
+		t.Error("Expected an error for an unknown algorithm")
+	}
+	if _, err := Simplify(nil, nil); err == nil {
+		t.Error("Expected an error for a nil geometry")
+	}
+}
+
+func TestATASimplify(t *testing.T) {
+	circle := geojson.NewPolygonGeometry([][][]float64{geodesicCircle([]float64{-71.06, 42.35}, 1000, 200)})
+	fc := geojson.NewFeatureCollection()
+	fc.AddFeature(geojson.NewFeature(circle))
+	fc.AddFeature(geojson.NewFeature(geojson.NewPointGeometry([]float64{-71.06, 42.35})))
+	j, err := json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var featureCollection geometry.FeatureCollection
+	if err := json.Unmarshal(j, &featureCollection); err != nil {
+		t.Fatal(err)
+	}
+	ata, err := (&ATA{&featureCollection}).Simplify(&SimplifyOptions{MaxVertices: 30, PreserveTopology: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	simplified, err := ata.GeoJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(simplified.Features) != 2 {
+		t.Fatal("Expected 2 features, got", len(simplified.Features))
+	}
+	if n := vertexCount(simplified.Features[0].Geometry) + vertexCount(simplified.Features[1].Geometry); n != 30 {
+		t.Errorf("Expected 30 vertices, got %d", n)
+	}
+}
+
+func BenchmarkSimplifyPreserveTopology(b *testing.B) {
+	// a wiggly ring of 20000 vertices, about as many as the largest ATAs
+	n := 20000
+	ring := make([][]float64, n+1)
+	for i := range ring[:n] {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		radius := 5000 + 200*math.Sin(50*angle) + 30*math.Sin(377*angle)
+		ring[i] = geodesicDestination([]float64{-71.06, 42.35}, 360*float64(i)/float64(n), radius)
+	}
+	ring[n] = ring[0]
+	polygon := geojson.NewPolygonGeometry([][][]float64{ring})
+	for _, algorithm := range []SimplifyAlgorithm{DouglasPeucker, VisvalingamWhyatt} {
+		b.Run([]string{"DouglasPeucker", "VisvalingamWhyatt"}[algorithm], func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Simplify(polygon, &SimplifyOptions{Algorithm: algorithm, MaxVertices: 500, PreserveTopology: true}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,610 @@
+package spatially
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// ValidationReason is the kind of issue Validate finds in a geometry
+type ValidationReason int
+
+const (
+	// InvalidCoordinate is a coordinate with fewer than 2 values or values which aren't finite numbers
+	InvalidCoordinate ValidationReason = iota
+	// OutOfRange is a longitude outside of [-180, 180] or a latitude outside of [-90, 90]
+	OutOfRange
+	// TooFewPoints is a linestring of fewer than 2 distinct points or a ring of fewer than 4 points
+	TooFewPoints
+	// UnclosedRing is a ring whose last point isn't its first
+	UnclosedRing
+	// DuplicatePoints are consecutive points at the same longitude & latitude
+	DuplicatePoints
+	// WrongOrientation is a shell which isn't counterclockwise or a hole which isn't clockwise, against
+	// the right hand rule of RFC 7946
+	WrongOrientation
+	// SelfIntersection is a ring crossing or touching itself, or rings of a polygon crossing each other
+	SelfIntersection
+	// HoleOutsideShell is a hole which isn't inside the shell of its polygon
+	HoleOutsideShell
+	// OverlappingPolygons are polygons of a MultiPolygon whose interiors intersect or which share edges
+	OverlappingPolygons
+)
+
+var validationReasons = []string{
+	"invalid coordinate",
+	"coordinate out of range",
+	"too few points",
+	"unclosed ring",
+	"duplicate points",
+	"wrong ring orientation",
+	"self intersection",
+	"hole outside shell",
+	"overlapping polygons",
+}
+
+func (r ValidationReason) String() string {
+	if r < 0 || int(r) >= len(validationReasons) {
+		return fmt.Sprintf("ValidationReason(%d)", int(r))
+	}
+	return validationReasons[r]
+}
+
+// repairable reports whether MakeValid can fix the issue
+func (r ValidationReason) repairable() bool {
+	return r != InvalidCoordinate && r != OutOfRange
+}
+
+// style reports whether the issue is in how the geometry is written rather than in its shape, which
+// MakeValid fixes but RejectInvalidGeometries lets through
+func (r ValidationReason) style() bool {
+	return r == DuplicatePoints || r == WrongOrientation
+}
+
+// ValidationIssue is an issue found by Validate
+type ValidationIssue struct {
+	Reason ValidationReason
+	// Path indexes the part of the geometry with the issue the way it's nested in GeoJSON, e.g. [2, 1]
+	// for the first hole of the third polygon of a MultiPolygon. Collections add the index of the
+	// geometry in front.
+	Path []int
+	// Point is where the issue is, nil when it's about a whole part
+	Point []float64
+}
+
+func (i ValidationIssue) String() string {
+	s := i.Reason.String()
+	if len(i.Path) > 0 {
+		s += fmt.Sprintf(" in part %v", i.Path)
+	}
+	if i.Point != nil {
+		s += fmt.Sprintf(" at %v", i.Point)
+	}
+	return s
+}
+
+// ValidationError lists the issues of an invalid geometry
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	const shown = 3
+	var issues []string
+	for i, issue := range e.Issues {
+		if i == shown {
+			issues = append(issues, fmt.Sprintf("%d more", len(e.Issues)-shown))
+			break
+		}
+		issues = append(issues, issue.String())
+	}
+	return "invalid geometry: " + strings.Join(issues, ", ")
+}
+
+// GeometryValidation is what a Client does with the geometries of the features it writes
+type GeometryValidation int
+
+const (
+	// SendGeometries as they are, leaving the API to reject invalid ones
+	SendGeometries GeometryValidation = iota
+	// RejectInvalidGeometries with a *ValidationError instead of sending them. Duplicate points and rings
+	// with the wrong orientation are sent as they are.
+	RejectInvalidGeometries
+	// RepairInvalidGeometries with MakeValid before sending them, failing with a *ValidationError when
+	// they can't be repaired
+	RepairInvalidGeometries
+)
+
+// WithGeometryValidation sets what the client does with the geometries of the features it creates. Clients
+// send geometries as they are by default.
+func WithGeometryValidation(validation GeometryValidation) ClientOption {
+	return func(c *Client) {
+		c.validation = validation
+	}
+}
+
+// prepareGeometry validates or repairs a geometry about to be written, as configured
+func (c *Client) prepareGeometry(g *geojson.Geometry) (*geojson.Geometry, error) {
+	switch c.validation {
+	case RejectInvalidGeometries:
+		err := Validate(g)
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		var issues []ValidationIssue
+		for _, issue := range validationErr.Issues {
+			if !issue.Reason.style() {
+				issues = append(issues, issue)
+			}
+		}
+		if len(issues) > 0 {
+			return nil, &ValidationError{Issues: issues}
+		}
+	case RepairInvalidGeometries:
+		return MakeValid(g)
+	}
+	return g, nil
+}
+
+// Validate checks that g is a valid OGC simple feature which also follows RFC 7946. It returns a
+// *ValidationError listing the issues it finds, or nil when there are none. Rings are tested in
+// longitude & latitude, where GeoJSON edges are straight lines. Empty geometries are valid.
+func Validate(g *geojson.Geometry) error {
+	v := &validator{}
+	if err := v.geometry(g, nil); err != nil {
+		return err
+	}
+	if len(v.issues) > 0 {
+		return &ValidationError{Issues: v.issues}
+	}
+	return nil
+}
+
+type validator struct {
+	issues []ValidationIssue
+	// reported are the issues found so far, so rings meeting at a point are reported once
+	reported map[string]bool
+	// overlapping are the pairs of polygons of a MultiPolygon whose edges cross
+	overlapping map[[2]int]bool
+}
+
+func (v *validator) report(reason ValidationReason, path []int, point []float64) {
+	issue := ValidationIssue{Reason: reason, Path: append([]int{}, path...), Point: point}
+	if v.reported == nil {
+		v.reported = map[string]bool{}
+	}
+	if key := issue.String(); !v.reported[key] {
+		v.reported[key] = true
+		v.issues = append(v.issues, issue)
+	}
+}
+
+func (v *validator) geometry(g *geojson.Geometry, path []int) error {
+	if g == nil {
+		return fmt.Errorf("nil geometry")
+	}
+	switch g.Type {
+	case geojson.GeometryPoint:
+		if len(g.Point) > 0 {
+			v.coordinate(g.Point, path)
+		}
+	case geojson.GeometryMultiPoint:
+		for i, p := range g.MultiPoint {
+			v.coordinate(p, append(path, i))
+		}
+	case geojson.GeometryLineString:
+		v.line(g.LineString, path)
+	case geojson.GeometryMultiLineString:
+		for i, line := range g.MultiLineString {
+			v.line(line, append(path, i))
+		}
+	case geojson.GeometryPolygon:
+		v.polygons([][][][]float64{g.Polygon}, path, false)
+	case geojson.GeometryMultiPolygon:
+		v.polygons(g.MultiPolygon, path, true)
+	case geojson.GeometryCollection:
+		for i, geometry := range g.Geometries {
+			if err := v.geometry(geometry, append(path, i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown or unimplemented geometry '%s'", g.Type)
+	}
+	return nil
+}
+
+// coordinate reports whether the coordinate is usable, reporting it when it isn't or when it's out of range
+func (v *validator) coordinate(p []float64, path []int) bool {
+	if len(p) < 2 || math.IsNaN(p[0]) || math.IsInf(p[0], 0) || math.IsNaN(p[1]) || math.IsInf(p[1], 0) {
+		v.report(InvalidCoordinate, path, p)
+		return false
+	}
+	if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+		v.report(OutOfRange, path, p)
+	}
+	return true
+}
+
+// points checks the coordinates of a linestring or ring and returns its distinct consecutive points, or
+// nil when some coordinates aren't usable
+func (v *validator) points(line [][]float64, path []int) []vec {
+	usable := true
+	var points []vec
+	for _, p := range line {
+		if !v.coordinate(p, path) {
+			usable = false
+			continue
+		}
+		if q := (vec{p[0], p[1]}); len(points) == 0 || points[len(points)-1] != q {
+			points = append(points, q)
+		} else {
+			v.report(DuplicatePoints, path, p)
+		}
+	}
+	if !usable {
+		return nil
+	}
+	return points
+}
+
+func (v *validator) line(line [][]float64, path []int) {
+	if points := v.points(line, path); len(line) > 0 && points != nil && len(points) < 2 {
+		v.report(TooFewPoints, path, nil)
+	}
+}
+
+// polygons checks the rings of polygons, then how the rings of each polygon and the polygons of a
+// MultiPolygon meet
+func (v *validator) polygons(polygons [][][][]float64, path []int, multi bool) {
+	var rings []validationRing
+	for i, polygon := range polygons {
+		polygonPath := path
+		if multi {
+			polygonPath = append(path, i)
+		}
+		for j, r := range polygon {
+			ringPath := append(append([]int{}, polygonPath...), j)
+			points := v.points(r, ringPath)
+			if points == nil {
+				continue
+			}
+			if len(r) < 4 {
+				v.report(TooFewPoints, ringPath, nil)
+				continue
+			}
+			if r[0][0] != r[len(r)-1][0] || r[0][1] != r[len(r)-1][1] {
+				v.report(UnclosedRing, ringPath, nil)
+				continue
+			}
+			if len(points) < 4 {
+				v.report(TooFewPoints, ringPath, nil)
+				continue
+			}
+			if area := ring(points).signedArea(); j == 0 && area < 0 || j > 0 && area > 0 {
+				v.report(WrongOrientation, ringPath, nil)
+			}
+			rings = append(rings, validationRing{polygon: i, index: j, path: ringPath, points: points})
+		}
+	}
+	v.overlapping = map[[2]int]bool{}
+	v.intersections(rings)
+	for _, r := range rings {
+		if r.index == 0 {
+			continue
+		}
+		for _, shell := range rings {
+			if shell.polygon == r.polygon && shell.index == 0 && locateOffRing(r.points, shell.points, nil) == exterior {
+				v.report(HoleOutsideShell, r.path, nil)
+			}
+		}
+	}
+	if !multi {
+		return
+	}
+	// polygons which don't cross are inside one another when a point of one is in the interior of the other
+	holes := map[int][][]vec{}
+	for _, r := range rings {
+		if r.index > 0 {
+			holes[r.polygon] = append(holes[r.polygon], r.points)
+		}
+	}
+	for _, a := range rings {
+		for _, b := range rings {
+			if a.index != 0 || b.index != 0 || a.polygon >= b.polygon || v.overlapping[[2]int{a.polygon, b.polygon}] {
+				continue
+			}
+			if locateOffRing(a.points, b.points, holes[b.polygon]) == interior ||
+				locateOffRing(b.points, a.points, holes[a.polygon]) == interior {
+				v.report(OverlappingPolygons, append(path, b.polygon), nil)
+			}
+		}
+	}
+}
+
+// validationRing is a closed ring without duplicate points
+type validationRing struct {
+	polygon, index int
+	path           []int
+	points         []vec
+}
+
+// locateOffRing finds where the first point of a ring which isn't on the shell or holes of a polygon is
+// relative to it, boundary when all of them are
+func locateOffRing(points []vec, shell []vec, holes [][]vec) location {
+	rings := append([][]vec{shell}, holes...)
+	for _, p := range points {
+		on := false
+		for _, r := range rings {
+			for i := 0; i+1 < len(r) && !on; i++ {
+				on = distanceToEdge(p, r[i], r[i+1]) == 0
+			}
+		}
+		if on {
+			continue
+		}
+		if !ring(shell).contains(p) {
+			return exterior
+		}
+		for _, hole := range holes {
+			if ring(hole).contains(p) {
+				return exterior
+			}
+		}
+		return interior
+	}
+	return boundary
+}
+
+// intersections reports the edges of rings which meet where they shouldn't. Edges of a ring may only meet
+// the edges before and after them at their ends, rings of a polygon may touch at points and polygons of a
+// MultiPolygon may touch at points.
+func (v *validator) intersections(rings []validationRing) {
+	type edge struct {
+		ring, index int
+		p, q        vec
+	}
+	var edges []edge
+	length := 0.0
+	extent := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for i, r := range rings {
+		for j := 0; j+1 < len(r.points); j++ {
+			edges = append(edges, edge{i, j, r.points[j], r.points[j+1]})
+			length += math.Hypot(r.points[j+1][0]-r.points[j][0], r.points[j+1][1]-r.points[j][1])
+		}
+		b := bounds(r.points)
+		extent[0], extent[1] = math.Min(extent[0], b[0]), math.Min(extent[1], b[1])
+		extent[2], extent[3] = math.Max(extent[2], b[2]), math.Max(extent[3], b[3])
+	}
+	if len(edges) == 0 {
+		return
+	}
+	index := newGridIndex(math.Max(2*length/float64(len(edges)), math.Max(extent[2]-extent[0], extent[3]-extent[1])/1024))
+	for i, e := range edges {
+		index.insert(i, bounds([]vec{e.p, e.q}))
+	}
+	seen := make([]int, len(edges))
+	for i, e := range edges {
+		index.query(bounds([]vec{e.p, e.q}), func(j int) {
+			if j <= i || seen[j] == i+1 {
+				return
+			}
+			seen[j] = i + 1
+			f := edges[j]
+			a, b := rings[e.ring], rings[f.ring]
+			switch {
+			case e.ring == f.ring:
+				n := len(a.points) - 1
+				if adjacent := f.index == e.index+1 || e.index == 0 && f.index == n-1; adjacent {
+					if segmentsConflict(e.p, e.q, f.p, f.q) {
+						v.report(SelfIntersection, a.path, meetingPoint(e.p, e.q, f.p, f.q))
+					}
+				} else if p := meetingPoint(e.p, e.q, f.p, f.q); p != nil {
+					v.report(SelfIntersection, a.path, p)
+				}
+			case a.polygon == b.polygon:
+				if segmentsCross(e.p, e.q, f.p, f.q) {
+					v.report(SelfIntersection, a.path, meetingPoint(e.p, e.q, f.p, f.q))
+				}
+			case !v.overlapping[[2]int{a.polygon, b.polygon}] && segmentsCross(e.p, e.q, f.p, f.q):
+				// overlapping polygons are reported once, at the second of them
+				v.overlapping[[2]int{a.polygon, b.polygon}] = true
+				v.overlapping[[2]int{b.polygon, a.polygon}] = true
+				if a.polygon > b.polygon {
+					b = a
+				}
+				v.report(OverlappingPolygons, b.path[:len(b.path)-1], meetingPoint(e.p, e.q, f.p, f.q))
+			}
+		})
+	}
+}
+
+// segmentsCross reports whether the segments p1 p2 and q1 q2 cross, or overlap along a stretch
+func segmentsCross(p1, p2, q1, q2 vec) bool {
+	o1, o2 := cross(p2.sub(p1), q1.sub(p1)), cross(p2.sub(p1), q2.sub(p1))
+	o3, o4 := cross(q2.sub(q1), p1.sub(q1)), cross(q2.sub(q1), p2.sub(q1))
+	if (o1 > 0 && o2 < 0 || o1 < 0 && o2 > 0) && (o3 > 0 && o4 < 0 || o3 < 0 && o4 > 0) {
+		return true
+	}
+	if o1 != 0 || o2 != 0 {
+		return false
+	}
+	// collinear segments overlap when their projections on the longer one do
+	d := p2.sub(p1)
+	l2 := d[0]*d[0] + d[1]*d[1]
+	if l2 == 0 {
+		return false
+	}
+	t1, t2 := q1.sub(p1), q2.sub(p1)
+	s1, s2 := (t1[0]*d[0]+t1[1]*d[1])/l2, (t2[0]*d[0]+t2[1]*d[1])/l2
+	return math.Min(1, math.Max(s1, s2)) > math.Max(0, math.Min(s1, s2))
+}
+
+// meetingPoint returns a point the segments p1 p2 and q1 q2 have in common, nil when they don't meet
+func meetingPoint(p1, p2, q1, q2 vec) []float64 {
+	for _, pair := range [][3]vec{{p1, q1, q2}, {p2, q1, q2}, {q1, p1, p2}, {q2, p1, p2}} {
+		if distanceToEdge(pair[0], pair[1], pair[2]) == 0 {
+			return []float64{pair[0][0], pair[0][1]}
+		}
+	}
+	r, s := p2.sub(p1), q2.sub(q1)
+	d := cross(r, s)
+	if d == 0 {
+		return nil
+	}
+	w := q1.sub(p1)
+	t, u := cross(w, s)/d, cross(w, r)/d
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return nil
+	}
+	return []float64{p1[0] + t*r[0], p1[1] + t*r[1]}
+}
+
+// MakeValid returns a copy of g without the issues Validate reports. Repeated points are removed, rings are
+// closed and oriented, and linestrings and rings with too few points are dropped. Polygons whose rings
+// cross are rebuilt from their rings with the even-odd rule, a point being inside when a ray cast from it
+// crosses the rings an odd number of times, and the polygons of a MultiPolygon which overlap are merged. A
+// Polygon can become a MultiPolygon and rebuilt polygons lose the Z values of their coordinates.
+// Coordinates which are invalid or out of range can't be repaired and are returned as a *ValidationError,
+// as is a geometry which collapses entirely.
+func MakeValid(g *geojson.Geometry) (*geojson.Geometry, error) {
+	v := &validator{}
+	if err := v.geometry(g, nil); err != nil {
+		return nil, err
+	}
+	var unrepairable []ValidationIssue
+	for _, issue := range v.issues {
+		if !issue.Reason.repairable() {
+			unrepairable = append(unrepairable, issue)
+		}
+	}
+	if len(unrepairable) > 0 {
+		return nil, &ValidationError{Issues: unrepairable}
+	}
+	if len(v.issues) == 0 {
+		return mapCoordinates(g, func(p []float64) ([]float64, error) { return p, nil })
+	}
+	repaired := repair(g)
+	if repaired == nil {
+		return nil, &ValidationError{Issues: v.issues}
+	}
+	return repaired, nil
+}
+
+// repair returns g without its issues, nil when nothing is left of it
+func repair(g *geojson.Geometry) *geojson.Geometry {
+	switch g.Type {
+	case geojson.GeometryPoint:
+		return geojson.NewPointGeometry(g.Point)
+	case geojson.GeometryMultiPoint:
+		return geojson.NewMultiPointGeometry(g.MultiPoint...)
+	case geojson.GeometryLineString:
+		if line := withoutDuplicates(g.LineString); len(line) >= 2 || len(g.LineString) == 0 {
+			return geojson.NewLineStringGeometry(line)
+		}
+	case geojson.GeometryMultiLineString:
+		var lines [][][]float64
+		for _, line := range g.MultiLineString {
+			if line = withoutDuplicates(line); len(line) >= 2 {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 || len(g.MultiLineString) == 0 {
+			return geojson.NewMultiLineStringGeometry(lines...)
+		}
+	case geojson.GeometryPolygon:
+		switch polygons := repairPolygons([][][][]float64{g.Polygon}); len(polygons) {
+		case 0:
+			if len(g.Polygon) == 0 {
+				return geojson.NewPolygonGeometry(nil)
+			}
+		case 1:
+			return geojson.NewPolygonGeometry(polygons[0])
+		default:
+			return geojson.NewMultiPolygonGeometry(polygons...)
+		}
+	case geojson.GeometryMultiPolygon:
+		if polygons := repairPolygons(g.MultiPolygon); len(polygons) > 0 || len(g.MultiPolygon) == 0 {
+			return geojson.NewMultiPolygonGeometry(polygons...)
+		}
+	case geojson.GeometryCollection:
+		var geometries []*geojson.Geometry
+		for _, geometry := range g.Geometries {
+			if repaired := repair(geometry); repaired != nil {
+				geometries = append(geometries, repaired)
+			}
+		}
+		if len(geometries) > 0 || len(g.Geometries) == 0 {
+			return geojson.NewCollectionGeometry(geometries...)
+		}
+	}
+	return nil
+}
+
+func withoutDuplicates(line [][]float64) [][]float64 {
+	var points [][]float64
+	for _, p := range line {
+		if len(points) == 0 || points[len(points)-1][0] != p[0] || points[len(points)-1][1] != p[1] {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// repairPolygons cleans and orients the rings of polygons, dropping polygons without a shell, and rebuilds
+// them when their rings cross or they overlap
+func repairPolygons(polygons [][][][]float64) [][][][]float64 {
+	var cleaned [][][][]float64
+	for _, polygon := range polygons {
+		var rings [][][]float64
+		for i, r := range polygon {
+			r = withoutDuplicates(r)
+			if len(r) > 0 && (r[0][0] != r[len(r)-1][0] || r[0][1] != r[len(r)-1][1]) {
+				r = append(r, r[0])
+			}
+			if len(r) < 4 {
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			// shells counterclockwise and holes clockwise
+			area := 0.0
+			for j := 0; j+1 < len(r); j++ {
+				area += r[j][0]*r[j+1][1] - r[j+1][0]*r[j][1]
+			}
+			if len(rings) == 0 && area < 0 || len(rings) > 0 && area > 0 {
+				reversed := make([][]float64, len(r))
+				for j, p := range r {
+					reversed[len(r)-1-j] = p
+				}
+				r = reversed
+			}
+			rings = append(rings, r)
+		}
+		if len(rings) > 0 {
+			cleaned = append(cleaned, rings)
+		}
+	}
+	v := &validator{}
+	v.polygons(cleaned, nil, true)
+	if len(v.issues) == 0 {
+		return cleaned
+	}
+
+	projection, originals := setProjection(cleaned)
+	var planar []planarPolygon
+	for _, polygon := range cleaned {
+		planar = append(planar, evenOddPolygons(projection.forwardPolygon(polygon))...)
+	}
+	if len(planar) > 1 {
+		planar = unionPolygons(planar)
+	}
+	return unprojectPolygons(projection, originals, planar)
+}
@@ -0,0 +1,198 @@
+package spatially
+
+import (
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	httpmock "gopkg.in/jarcoal/httpmock.v1"
+)
+
+func reasonsOf(err error) []ValidationReason {
+	validationErr, ok := errors.Cause(err).(*ValidationError)
+	if !ok {
+		return nil
+	}
+	var reasons []ValidationReason
+	for _, issue := range validationErr.Issues {
+		reasons = append(reasons, issue.Reason)
+	}
+	return reasons
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		geometry *geojson.Geometry
+		reasons  []ValidationReason
+	}{
+		{geojson.NewPointGeometry([]float64{-71.06, 42.35}), nil},
+		{geojson.NewPointGeometry([]float64{-71.06, 92}), []ValidationReason{OutOfRange}},
+		{geojson.NewPointGeometry([]float64{math.NaN(), 42.35}), []ValidationReason{InvalidCoordinate}},
+		{geojson.NewMultiPointGeometry([]float64{1, 2}, []float64{3}), []ValidationReason{InvalidCoordinate}},
+		{geojson.NewLineStringGeometry([][]float64{{0, 0}, {1, 1}, {1, 1}}), []ValidationReason{DuplicatePoints}},
+		{geojson.NewLineStringGeometry([][]float64{{0, 0}, {0, 0}}), []ValidationReason{DuplicatePoints, TooFewPoints}},
+		{geojson.NewLineStringGeometry(nil), nil},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}), nil},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}), []ValidationReason{UnclosedRing}},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {0, 0}}}), []ValidationReason{TooFewPoints}},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}), []ValidationReason{WrongOrientation}},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {1, 0}, {1, 1}, {0, 0}}}), []ValidationReason{DuplicatePoints}},
+		{geojson.NewPolygonGeometry(nil), nil},
+	} {
+		if reasons := reasonsOf(Validate(test.geometry)); !reflect.DeepEqual(reasons, test.reasons) {
+			t.Errorf("Expected %v for %+v, got %v", test.reasons, test.geometry, reasons)
+		}
+	}
+	if err := Validate(nil); err == nil {
+		t.Error("Expected an error for a nil geometry")
+	}
+}
+
+func TestValidateTopology(t *testing.T) {
+	for _, test := range []struct {
+		wkt     string
+		reasons []ValidationReason
+	}{
+		// a bow tie
+		{"POLYGON((0 0,1 1,1 0,0 1,0 0))", []ValidationReason{SelfIntersection}},
+		// a spike back along the last edge
+		{"POLYGON((0 0,2 0,2 2,0 2,0 3,0 0))", []ValidationReason{SelfIntersection}},
+		// a ring touching itself at a vertex
+		{"POLYGON((0 0,4 0,2 2,3 4,1 4,2 2,0 0))", []ValidationReason{SelfIntersection}},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(1 1,1 3,3 3,3 1,1 1))", nil},
+		// holes may touch their shell at a point
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(0 0,1 3,3 3,0 0))", nil},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(3 1,3 5,5 5,5 1,3 1))", []ValidationReason{SelfIntersection, SelfIntersection}},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(5 1,5 3,7 3,7 1,5 1))", []ValidationReason{HoleOutsideShell}},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((1 1,2 1,2 2,1 2,1 1)))", nil},
+		{"MULTIPOLYGON(((0 0,2 0,2 2,0 2,0 0)),((1 1,3 1,3 3,1 3,1 1)))", []ValidationReason{OverlappingPolygons}},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((1 0,2 0,2 1,1 1,1 0)))", []ValidationReason{OverlappingPolygons}},
+		{"MULTIPOLYGON(((0 0,4 0,4 4,0 4,0 0)),((1 1,2 1,2 2,1 2,1 1)))", []ValidationReason{OverlappingPolygons}},
+		// a polygon in the hole of another
+		{"MULTIPOLYGON(((0 0,4 0,4 4,0 4,0 0),(1 1,1 3,3 3,3 1,1 1)),((1.5 1.5,2.5 1.5,2.5 2.5,1.5 2.5,1.5 1.5)))", nil},
+		{"GEOMETRYCOLLECTION(POINT(0 0),POLYGON((0 0,1 1,1 0,0 1,0 0)))", []ValidationReason{SelfIntersection}},
+	} {
+		if reasons := reasonsOf(Validate(mustWKT(t, test.wkt))); !reflect.DeepEqual(reasons, test.reasons) {
+			t.Errorf("Expected %v for %s, got %v", test.reasons, test.wkt, reasons)
+		}
+	}
+	err := Validate(mustWKT(t, "MULTIPOLYGON(((0 0,2 0,2 2,0 2,0 0)),((3 0,4 1,4 0,3 1,3 0),(3.2 0.4,3.4 0.4,3.4 0.6,3.2 0.4)))"))
+	issues := err.(*ValidationError).Issues
+	if len(issues) == 0 || !reflect.DeepEqual(issues[0].Path, []int{1, 1}) || !reflect.DeepEqual(issues[1].Point, []float64{3.5, 0.5}) {
+		t.Errorf("Unexpected issues %+v", issues)
+	}
+	if err.Error() != "invalid geometry: wrong ring orientation in part [1 1], self intersection in part [1 0] at [3.5 0.5]" {
+		t.Error("Unexpected message", err)
+	}
+	err = Validate(mustWKT(t, "MULTIPOLYGON(((0 0,2 0,2 2,0 2,0 0)),((1 1,3 1,3 3,1 3,1 1)))"))
+	if issues := err.(*ValidationError).Issues; len(issues) != 1 || !reflect.DeepEqual(issues[0].Path, []int{1}) {
+		t.Errorf("Unexpected issues %+v", issues)
+	}
+}
+
+func TestMakeValid(t *testing.T) {
+	for _, test := range []struct {
+		wkt   string
+		area  float64
+		parts int
+	}{
+		{"POLYGON((0 0,1 0,1 1,0 1,0 0))", 1, 1},
+		{"POLYGON((0 0,0 1,1 1,1 1,1 0,0 0))", 1, 1},
+		{"POLYGON((0 0,1 1,1 0,0 1,0 0))", 0.5, 2},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(3 1,3 5,5 5,5 1,3 1))", 16 - 3 + 5, 2},
+		{"POLYGON((0 0,4 0,4 4,0 4,0 0),(5 1,5 3,7 3,7 1,5 1))", 16 + 4, 2},
+		{"MULTIPOLYGON(((0 0,2 0,2 2,0 2,0 0)),((1 1,3 1,3 3,1 3,1 1)))", 7, 1},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 1,0 0)),((1 0,2 0,2 1,1 1,1 0)))", 2, 1},
+		{"GEOMETRYCOLLECTION(LINESTRING(0 0,0 0),POLYGON((0 0,1 0,1 1,0 1,0 0)))", 1, 1},
+	} {
+		g := mustWKT(t, test.wkt)
+		valid, err := MakeValid(g)
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		if err := Validate(valid); err != nil {
+			t.Error(test.wkt, err)
+		}
+		_, _, polygons := flatten(valid)
+		planar := 0.0
+		for _, polygon := range polygons {
+			for _, r := range polygon {
+				var points ring
+				for _, p := range r {
+					points = append(points, vec{p[0], p[1]})
+				}
+				planar += points.signedArea()
+			}
+		}
+		if math.Abs(planar-test.area) > 1e-6 || len(polygons) != test.parts {
+			wkt, _ := GeometryToWKT(valid)
+			t.Errorf("Expected %d polygons of area %f for %s, got %s", test.parts, test.area, test.wkt, wkt)
+		}
+	}
+	// vertices are kept as they are
+	unclosed := geojson.NewPolygonGeometry([][][]float64{{{-71.1, 42.3}, {-71.0, 42.3}, {-71.0, 42.4}}})
+	valid, err := MakeValid(unclosed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wkt, _ := GeometryToWKT(valid); wkt != "POLYGON((-71.1 42.3,-71 42.3,-71 42.4,-71.1 42.3))" {
+		t.Error("Unexpected repair", wkt)
+	}
+	if _, err := MakeValid(geojson.NewPointGeometry([]float64{200, 0})); !reflect.DeepEqual(reasonsOf(err), []ValidationReason{OutOfRange}) {
+		t.Error("Expected a coordinate out of range, got", err)
+	}
+	if _, err := MakeValid(mustWKT(t, "LINESTRING(1 1,1 1)")); err == nil {
+		t.Error("Expected an error for a collapsed linestring")
+	}
+}
+
+func TestCreateFeatureGeometryValidation(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockGatewayEndpoint(t)
+	bowtie := mustWKT(t, "POLYGON((-71.06 42.35,-71.05 42.36,-71.05 42.35,-71.06 42.36,-71.06 42.35))")
+	properties := map[string]interface{}{"name": "Starbucks"}
+	layerID := uuid.NewUUID().String()
+	mockCreateFeatureEndpoint(t, layerID, uuid.NewUUID().String())
+
+	sdb, err := NewAPI(applicationCode, applicationKey, WithGeometryValidation(RejectInvalidGeometries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpmock.RegisterResponder("POST", SpatiallyAPI+"/spatialdb/feature", func(req *http.Request) (*http.Response, error) {
+		t.Error("Expected the invalid geometry not to be sent")
+		return httpmock.NewStringResponse(500, ""), nil
+	})
+	err = NewFeature().Create(sdb, layerID, bowtie, properties)
+	if reasons := reasonsOf(err); !reflect.DeepEqual(reasons, []ValidationReason{SelfIntersection}) {
+		t.Error("Expected a self intersection, got", err)
+	}
+	// clockwise shells and duplicate points are sent as they are
+	mockCreateFeatureEndpoint(t, layerID, uuid.NewUUID().String())
+	clockwise := mustWKT(t, "POLYGON((-71.06 42.35,-71.06 42.36,-71.05 42.36,-71.05 42.36,-71.05 42.35,-71.06 42.35))")
+	if reasons := reasonsOf(Validate(clockwise)); !reflect.DeepEqual(reasons, []ValidationReason{DuplicatePoints, WrongOrientation}) {
+		t.Fatal("Expected duplicate points and a wrong orientation, got", reasons)
+	}
+	feature := NewFeature()
+	if err := feature.Create(sdb, layerID, clockwise, properties); err != nil {
+		t.Fatal(err)
+	}
+
+	sdb, err = NewAPI(applicationCode, applicationKey, WithGeometryValidation(RepairInvalidGeometries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockCreateFeatureEndpoint(t, layerID, uuid.NewUUID().String())
+	feature = NewFeature()
+	if err := feature.Create(sdb, layerID, bowtie, properties); err != nil {
+		t.Fatal(err)
+	}
+	if feature.Geometry.Type != geojson.GeometryMultiPolygon || Validate(feature.Geometry) != nil {
+		t.Errorf("Expected the repaired geometry to be sent, got %+v", feature.Geometry)
+	}
+}
@@ -0,0 +1,486 @@
+package spatially
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// WKB geometry type codes
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// EWKB flags set on the geometry type
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+var wkbTypes = map[uint32]geojson.GeometryType{
+	wkbPoint:              geojson.GeometryPoint,
+	wkbLineString:         geojson.GeometryLineString,
+	wkbPolygon:            geojson.GeometryPolygon,
+	wkbMultiPoint:         geojson.GeometryMultiPoint,
+	wkbMultiLineString:    geojson.GeometryMultiLineString,
+	wkbMultiPolygon:       geojson.GeometryMultiPolygon,
+	wkbGeometryCollection: geojson.GeometryCollection,
+}
+
+// WKBOptions configures the binary written by GeometryToWKB
+type WKBOptions struct {
+	// ByteOrder of the binary, binary.LittleEndian when nil
+	ByteOrder binary.ByteOrder
+	// Extended writes EWKB, as used by PostGIS, instead of ISO WKB
+	Extended bool
+	// SRID is written with Extended binary when it isn't 0
+	SRID int
+}
+
+// WKBToGeometry converts Well Known Binary into a geojson geometry. It reads both byte orders, the
+// ISO codes for Z, M and ZM geometries and EWKB. As with WKT, M values of M coordinates are dropped
+// and ZM coordinates keep them as their fourth element.
+func WKBToGeometry(wkb []byte) (*geojson.Geometry, error) {
+	g, _, err := EWKBToGeometry(wkb)
+	return g, err
+}
+
+// EWKBToGeometry converts Extended Well Known Binary into a geojson geometry and the SRID of its
+// coordinates, 0 when the binary has none. The coordinates are not reprojected, see ReprojectToWGS84.
+func EWKBToGeometry(wkb []byte) (g *geojson.Geometry, srid int, err error) {
+	r := &wkbReader{data: wkb}
+	g, srid, err = r.readGeometry(true)
+	if err != nil {
+		return nil, 0, err
+	}
+	if r.i != len(r.data) {
+		return nil, 0, fmt.Errorf("wkb has %d unexpected bytes after geometry", len(r.data)-r.i)
+	}
+	return g, srid, nil
+}
+
+type wkbReader struct {
+	data  []byte
+	i     int
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) readUint32() (uint32, error) {
+	if len(r.data)-r.i < 4 {
+		return 0, fmt.Errorf("wkb ends unexpectedly at byte %d", r.i)
+	}
+	v := r.order.Uint32(r.data[r.i:])
+	r.i += 4
+	return v, nil
+}
+
+func (r *wkbReader) readFloat() (float64, error) {
+	if len(r.data)-r.i < 8 {
+		return 0, fmt.Errorf("wkb ends unexpectedly at byte %d", r.i)
+	}
+	v := math.Float64frombits(r.order.Uint64(r.data[r.i:]))
+	r.i += 8
+	return v, nil
+}
+
+// readCount reads the number of elements that follow, checking there are enough bytes left for them
+func (r *wkbReader) readCount(minSize int) (int, error) {
+	n, err := r.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(minSize) > uint64(len(r.data)-r.i) {
+		return 0, fmt.Errorf("wkb count %d at byte %d exceeds the remaining %d bytes", n, r.i-4, len(r.data)-r.i)
+	}
+	return int(n), nil
+}
+
+// readHeader reads the byte order and geometry type of a geometry
+func (r *wkbReader) readHeader() (geometryType geojson.GeometryType, dim dimension, srid int, err error) {
+	if r.i >= len(r.data) {
+		return "", dimensionAny, 0, fmt.Errorf("wkb ends unexpectedly at byte %d", r.i)
+	}
+	switch r.data[r.i] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return "", dimensionAny, 0, fmt.Errorf("invalid wkb byte order %d at byte %d", r.data[r.i], r.i)
+	}
+	r.i++
+	code, err := r.readUint32()
+	if err != nil {
+		return "", dimensionAny, 0, err
+	}
+	hasZ, hasM := code&ewkbZ != 0, code&ewkbM != 0
+	if code&ewkbSRID != 0 {
+		s, err := r.readUint32()
+		if err != nil {
+			return "", dimensionAny, 0, err
+		}
+		srid = int(s)
+	}
+	code &^= ewkbZ | ewkbM | ewkbSRID
+	switch code / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	}
+	geometryType, known := wkbTypes[code%1000]
+	if !known || code >= 4000 {
+		return "", dimensionAny, 0, fmt.Errorf("unknown or unimplemented wkb geometry type %d", code)
+	}
+	switch {
+	case hasZ && hasM:
+		dim = dimensionZM
+	case hasZ:
+		dim = dimensionZ
+	case hasM:
+		dim = dimensionM
+	}
+	return geometryType, dim, srid, nil
+}
+
+func (r *wkbReader) readGeometry(allowSRID bool) (*geojson.Geometry, int, error) {
+	geometryType, dim, srid, err := r.readHeader()
+	if err != nil {
+		return nil, 0, err
+	}
+	if srid != 0 && !allowSRID {
+		return nil, 0, fmt.Errorf("wkb member geometries can't have an SRID")
+	}
+	var g *geojson.Geometry
+	switch geometryType {
+	case geojson.GeometryPoint:
+		var p []float64
+		if p, err = r.readCoordinate(dim); err == nil {
+			g = &geojson.Geometry{Type: geojson.GeometryPoint}
+			if !math.IsNaN(p[0]) || !math.IsNaN(p[1]) {
+				g.Point = p
+			}
+		}
+	case geojson.GeometryLineString:
+		var ls [][]float64
+		if ls, err = r.readLineString(dim); err == nil {
+			g = geojson.NewLineStringGeometry(ls)
+		}
+	case geojson.GeometryPolygon:
+		var polygon [][][]float64
+		if polygon, err = r.readPolygon(dim); err == nil {
+			g = geojson.NewPolygonGeometry(polygon)
+		}
+	default:
+		var n int
+		if n, err = r.readCount(1 + 4); err != nil {
+			return nil, 0, err
+		}
+		g = emptyGeometry(geometryType)
+		for i := 0; i < n && err == nil; i++ {
+			var member *geojson.Geometry
+			if member, _, err = r.readGeometry(false); err != nil {
+				break
+			}
+			err = addMember(g, member)
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return g, srid, nil
+}
+
+// addMember adds a geometry read from WKB to the multi geometry or collection g
+func addMember(g, member *geojson.Geometry) error {
+	switch {
+	case g.Type == geojson.GeometryMultiPoint && member.Type == geojson.GeometryPoint:
+		if len(member.Point) == 0 {
+			return fmt.Errorf("empty points in a multipoint are not supported")
+		}
+		g.MultiPoint = append(g.MultiPoint, member.Point)
+	case g.Type == geojson.GeometryMultiLineString && member.Type == geojson.GeometryLineString:
+		g.MultiLineString = append(g.MultiLineString, member.LineString)
+	case g.Type == geojson.GeometryMultiPolygon && member.Type == geojson.GeometryPolygon:
+		g.MultiPolygon = append(g.MultiPolygon, member.Polygon)
+	case g.Type == geojson.GeometryCollection:
+		g.Geometries = append(g.Geometries, member)
+	default:
+		return fmt.Errorf("a %s can't contain a %s", g.Type, member.Type)
+	}
+	return nil
+}
+
+func (r *wkbReader) readCoordinate(dim dimension) ([]float64, error) {
+	size := dim.size()
+	if size == 0 {
+		size = 2
+	}
+	p := make([]float64, size)
+	for i := range p {
+		f, err := r.readFloat()
+		if err != nil {
+			return nil, err
+		}
+		p[i] = f
+	}
+	if dim == dimensionM {
+		p = p[:2]
+	}
+	return p, nil
+}
+
+func (r *wkbReader) readLineString(dim dimension) ([][]float64, error) {
+	size := dim.size()
+	if size == 0 {
+		size = 2
+	}
+	n, err := r.readCount(8 * size)
+	if err != nil {
+		return nil, err
+	}
+	ls := make([][]float64, n)
+	for i := range ls {
+		if ls[i], err = r.readCoordinate(dim); err != nil {
+			return nil, err
+		}
+	}
+	return ls, nil
+}
+
+func (r *wkbReader) readPolygon(dim dimension) ([][][]float64, error) {
+	n, err := r.readCount(4)
+	if err != nil {
+		return nil, err
+	}
+	polygon := make([][][]float64, n)
+	for i := range polygon {
+		if polygon[i], err = r.readLineString(dim); err != nil {
+			return nil, err
+		}
+	}
+	return polygon, nil
+}
+
+// GeometryToWKB converts a geojson geometry into Well Known Binary. Without options it writes little
+// endian ISO WKB. Geometries with 3d coordinates are written as Z geometries and those with 4d
+// coordinates as ZM geometries, and collections take the dimension of their members.
+func GeometryToWKB(g *geojson.Geometry, options *WKBOptions) ([]byte, error) {
+	if options == nil {
+		options = &WKBOptions{}
+	}
+	w := &wkbWriter{order: options.ByteOrder, extended: options.Extended}
+	if w.order == nil {
+		w.order = binary.LittleEndian
+	}
+	// the marker is 1 for little endian, the order of the low byte written first
+	var probe [2]byte
+	w.order.PutUint16(probe[:], 1)
+	w.marker = probe[0]
+	srid := 0
+	if options.Extended {
+		srid = options.SRID
+	}
+	if err := w.writeGeometry(g, 0, srid); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+type wkbWriter struct {
+	buf      []byte
+	order    binary.ByteOrder
+	marker   byte
+	extended bool
+}
+
+func (w *wkbWriter) writeUint32(v uint32) {
+	var b [4]byte
+	w.order.PutUint32(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *wkbWriter) writeFloat(f float64) {
+	var b [8]byte
+	w.order.PutUint64(b[:], math.Float64bits(f))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *wkbWriter) writeHeader(code uint32, size, srid int) {
+	w.buf = append(w.buf, w.marker)
+	switch {
+	case w.extended && size == 3:
+		code |= ewkbZ
+	case w.extended && size == 4:
+		code |= ewkbZ | ewkbM
+	case size == 3:
+		code += 1000
+	case size == 4:
+		code += 3000
+	}
+	if srid != 0 {
+		code |= ewkbSRID
+	}
+	w.writeUint32(code)
+	if srid != 0 {
+		w.writeUint32(uint32(srid))
+	}
+}
+
+// coordinateSize finds the number of values in the coordinates of g from its first coordinate, 2
+// when g is empty
+func coordinateSize(g *geojson.Geometry) int {
+	if p := firstCoordinate(g); len(p) >= 2 {
+		return len(p)
+	}
+	return 2
+}
+
+// firstCoordinate returns the first coordinate of g, nil when g is empty
+func firstCoordinate(g *geojson.Geometry) []float64 {
+	var p []float64
+	switch {
+	case len(g.Point) > 0:
+		p = g.Point
+	case len(g.MultiPoint) > 0:
+		p = g.MultiPoint[0]
+	case len(g.LineString) > 0:
+		p = g.LineString[0]
+	case len(g.MultiLineString) > 0 && len(g.MultiLineString[0]) > 0:
+		p = g.MultiLineString[0][0]
+	case len(g.Polygon) > 0 && len(g.Polygon[0]) > 0:
+		p = g.Polygon[0][0]
+	case len(g.MultiPolygon) > 0 && len(g.MultiPolygon[0]) > 0 && len(g.MultiPolygon[0][0]) > 0:
+		p = g.MultiPolygon[0][0][0]
+	}
+	return p
+}
+
+// collectionSize finds the number of values in the coordinates of the members of a collection from the
+// first member with coordinates, 2 when there is none
+func collectionSize(g *geojson.Geometry) int {
+	for _, member := range g.Geometries {
+		switch {
+		case member == nil:
+		case member.Type == geojson.GeometryCollection:
+			if size := collectionSize(member); size != 2 {
+				return size
+			}
+		case len(firstCoordinate(member)) >= 2:
+			return coordinateSize(member)
+		}
+	}
+	return 2
+}
+
+// writeGeometry writes g with coordinates of the given size, or of the size of its own coordinates when
+// size is 0. Members of collections have the size of the collection.
+func (w *wkbWriter) writeGeometry(g *geojson.Geometry, size, srid int) error {
+	if g == nil {
+		return fmt.Errorf("nil geometry")
+	}
+	if size == 0 && g.Type == geojson.GeometryCollection {
+		size = collectionSize(g)
+	} else if size == 0 {
+		size = coordinateSize(g)
+	}
+	switch g.Type {
+	case geojson.GeometryPoint:
+		w.writeHeader(wkbPoint, size, srid)
+		if len(g.Point) == 0 {
+			for i := 0; i < size; i++ {
+				w.writeFloat(math.NaN())
+			}
+			return nil
+		}
+		return w.writeCoordinate(g.Point, size)
+	case geojson.GeometryLineString:
+		w.writeHeader(wkbLineString, size, srid)
+		return w.writeLineString(g.LineString, size)
+	case geojson.GeometryPolygon:
+		w.writeHeader(wkbPolygon, size, srid)
+		return w.writePolygon(g.Polygon, size)
+	case geojson.GeometryMultiPoint:
+		w.writeHeader(wkbMultiPoint, size, srid)
+		w.writeUint32(uint32(len(g.MultiPoint)))
+		for _, p := range g.MultiPoint {
+			w.writeHeader(wkbPoint, size, 0)
+			if err := w.writeCoordinate(p, size); err != nil {
+				return err
+			}
+		}
+	case geojson.GeometryMultiLineString:
+		w.writeHeader(wkbMultiLineString, size, srid)
+		w.writeUint32(uint32(len(g.MultiLineString)))
+		for _, ls := range g.MultiLineString {
+			w.writeHeader(wkbLineString, size, 0)
+			if err := w.writeLineString(ls, size); err != nil {
+				return err
+			}
+		}
+	case geojson.GeometryMultiPolygon:
+		w.writeHeader(wkbMultiPolygon, size, srid)
+		w.writeUint32(uint32(len(g.MultiPolygon)))
+		for _, polygon := range g.MultiPolygon {
+			w.writeHeader(wkbPolygon, size, 0)
+			if err := w.writePolygon(polygon, size); err != nil {
+				return err
+			}
+		}
+	case geojson.GeometryCollection:
+		w.writeHeader(wkbGeometryCollection, size, srid)
+		w.writeUint32(uint32(len(g.Geometries)))
+		for _, member := range g.Geometries {
+			if err := w.writeGeometry(member, size, 0); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown or unimplemented geometry '%s'", g.Type)
+	}
+	return nil
+}
+
+func (w *wkbWriter) writeCoordinate(p []float64, size int) error {
+	if len(p) != size {
+		return fmt.Errorf("point must have %d elements like the rest of the geometry, got %d", size, len(p))
+	}
+	if size > 4 {
+		return fmt.Errorf("point can be at most 4d. got %d elements", size)
+	}
+	for _, f := range p {
+		w.writeFloat(f)
+	}
+	return nil
+}
+
+func (w *wkbWriter) writeLineString(ls [][]float64, size int) error {
+	w.writeUint32(uint32(len(ls)))
+	for _, p := range ls {
+		if err := w.writeCoordinate(p, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *wkbWriter) writePolygon(polygon [][][]float64, size int) error {
+	w.writeUint32(uint32(len(polygon)))
+	for _, ring := range polygon {
+		if err := w.writeLineString(ring, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package spatially
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestWKBToGeometry(t *testing.T) {
+	for _, test := range []struct {
+		wkb     string
+		geojson string
+	}{
+		// POINT(1 2) in little and big endian
+		{"0101000000000000000000F03F0000000000000040", `{"type":"Point","coordinates":[1,2]}`},
+		{"00000000013FF00000000000004000000000000000", `{"type":"Point","coordinates":[1,2]}`},
+		// ISO POINT Z (1 2 3), POINT M (1 2 3) and POINT ZM (1 2 3 4)
+		{"01E9030000000000000000F03F00000000000000400000000000000840", `{"type":"Point","coordinates":[1,2,3]}`},
+		{"01D1070000000000000000F03F00000000000000400000000000000840", `{"type":"Point","coordinates":[1,2]}`},
+		{"01B90B0000000000000000F03F000000000000004000000000000008400000000000001040", `{"type":"Point","coordinates":[1,2,3,4]}`},
+		// EWKB POINT Z (1 2 3)
+		{"0101000080000000000000F03F00000000000000400000000000000840", `{"type":"Point","coordinates":[1,2,3]}`},
+		// POINT EMPTY
+		{"0101000000000000000000F87F000000000000F87F", `{"type":"Point","coordinates":null}`},
+		// LINESTRING(1 2,3 4)
+		{"010200000002000000000000000000F03F000000000000004000000000000008400000000000001040", `{"type":"LineString","coordinates":[[1,2],[3,4]]}`},
+		// MULTIPOINT((1 2),(3 4)) with big endian members
+		{"0104000000020000000000000001" + "3FF00000000000004000000000000000" + "00000000014008000000000000" + "4010000000000000", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+		// GEOMETRYCOLLECTION EMPTY
+		{"010700000000000000", `{"type":"GeometryCollection","geometries":[]}`},
+	} {
+		b, err := hex.DecodeString(test.wkb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := WKBToGeometry(b)
+		if err != nil {
+			t.Error(test.wkb, err)
+			continue
+		}
+		j, err := json.Marshal(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canonicalJSON(t, j) != canonicalJSON(t, []byte(test.geojson)) {
+			t.Error(test.wkb, "expected", test.geojson, "got", string(j))
+		}
+	}
+}
+
+func TestEWKBToGeometrySRID(t *testing.T) {
+	// SRID=4326;POINT(1 2) as written by PostGIS
+	b, _ := hex.DecodeString("0101000020E6100000000000000000F03F0000000000000040")
+	g, srid, err := EWKBToGeometry(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srid != 4326 {
+		t.Error("Expected SRID 4326, got", srid)
+	}
+	if len(g.Point) != 2 || g.Point[0] != 1 || g.Point[1] != 2 {
+		t.Error("Expected POINT(1 2), got", g.Point)
+	}
+}
+
+// bigEndian is a big endian binary.ByteOrder other than binary.BigEndian
+type bigEndian struct{}
+
+func (bigEndian) Uint16(b []byte) uint16       { return binary.BigEndian.Uint16(b) }
+func (bigEndian) PutUint16(b []byte, v uint16) { binary.BigEndian.PutUint16(b, v) }
+func (bigEndian) Uint32(b []byte) uint32       { return binary.BigEndian.Uint32(b) }
+func (bigEndian) PutUint32(b []byte, v uint32) { binary.BigEndian.PutUint32(b, v) }
+func (bigEndian) Uint64(b []byte) uint64       { return binary.BigEndian.Uint64(b) }
+func (bigEndian) PutUint64(b []byte, v uint64) { binary.BigEndian.PutUint64(b, v) }
+func (bigEndian) String() string               { return "bigEndian" }
+
+func TestGeometryToWKB(t *testing.T) {
+	g, err := WKTToGeometry("POINT(1 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		options *WKBOptions
+		wkb     string
+	}{
+		{nil, "0101000000000000000000F03F0000000000000040"},
+		{&WKBOptions{ByteOrder: binary.BigEndian}, "00000000013FF00000000000004000000000000000"},
+		{&WKBOptions{ByteOrder: bigEndian{}}, "00000000013FF00000000000004000000000000000"},
+		{&WKBOptions{Extended: true, SRID: 4326}, "0101000020E6100000000000000000F03F0000000000000040"},
+		// the SRID is only written in EWKB
+		{&WKBOptions{SRID: 4326}, "0101000000000000000000F03F0000000000000040"},
+	} {
+		b, err := GeometryToWKB(g, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(b)); got != test.wkb {
+			t.Errorf("Expected %s with %+v, got %s", test.wkb, test.options, got)
+		}
+	}
+}
+
+func TestGeometryToWKBRoundTrip(t *testing.T) {
+	for _, test := range wktConformance {
+		g, err := WKTToGeometry(test.wkt)
+		if err != nil {
+			t.Fatal(test.wkt, err)
+		}
+		expected, _ := json.Marshal(g)
+		for _, options := range []*WKBOptions{
+			nil,
+			{ByteOrder: binary.BigEndian},
+			{Extended: true, SRID: 3857},
+			{Extended: true, ByteOrder: binary.BigEndian},
+		} {
+			b, err := GeometryToWKB(g, options)
+			if err != nil {
+				t.Error(test.wkt, err)
+				continue
+			}
+			read, srid, err := EWKBToGeometry(b)
+			if err != nil {
+				t.Error(test.wkt, options, err)
+				continue
+			}
+			if options != nil && srid != options.SRID {
+				t.Errorf("Expected SRID %d for %s, got %d", options.SRID, test.wkt, srid)
+			}
+			j, _ := json.Marshal(read)
+			if canonicalJSON(t, j) != canonicalJSON(t, expected) {
+				t.Error(test.wkt, "expected", string(expected), "got", string(j))
+			}
+		}
+	}
+}
+
+func TestGeometryToWKBCollectionDimension(t *testing.T) {
+	g, err := WKTToGeometry("GEOMETRYCOLLECTION(POINT EMPTY,POINT Z (1 2 3),LINESTRING Z (1 2 3,4 5 6))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		options *WKBOptions
+		header  string
+	}{
+		// ISO WKB GeometryCollection Z
+		{nil, "01EF030000"},
+		// EWKB GeometryCollection with the Z flag
+		{&WKBOptions{Extended: true}, "0107000080"},
+	} {
+		b, err := GeometryToWKB(g, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header := strings.ToUpper(hex.EncodeToString(b[:5])); header != test.header {
+			t.Errorf("Expected the header %s with %+v, got %s", test.header, test.options, header)
+		}
+		read, _, err := EWKBToGeometry(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := json.Marshal(g)
+		j, _ := json.Marshal(read)
+		if canonicalJSON(t, j) != canonicalJSON(t, expected) {
+			t.Error("Expected", string(expected), "got", string(j))
+		}
+	}
+	g.Geometries = append(g.Geometries, geojson.NewPointGeometry([]float64{1, 2}))
+	if _, err := GeometryToWKB(g, nil); err == nil {
+		t.Error("Expected an error for members of different dimensions")
+	}
+}
+
+func TestWKBToGeometryInvalid(t *testing.T) {
+	for _, wkb := range []string{
+		"",
+		"01",
+		"0201000000000000000000F03F0000000000000040",
+		"0108000000",
+		"0101000000000000000000F03F",
+		"0101000000000000000000F03F000000000000004000",
+		// a linestring claiming more points than there are bytes
+		"0102000000FFFFFFFF",
+		// a multipoint containing a linestring
+		"010400000001000000010200000000000000",
+		// an SRID on a member geometry
+		"0107000000010000000101000020E6100000000000000000F03F0000000000000040",
+	} {
+		b, err := hex.DecodeString(wkb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, err := WKBToGeometry(b); err == nil {
+			t.Errorf("Expected an error for %s, got %+v", wkb, g)
+		}
+	}
+}
@@ -3,12 +3,18 @@ package spatially
 import (
 	"bytes"
 	"fmt"
-	"reflect"
+	"strconv"
+	"strings"
 
 	geojson "github.com/paulmach/go.geojson"
 )
 
-// WKTToGeometry converts a given Well Known Text shape into a geojson geometry
+// WKTToGeometry converts a given Well Known Text shape into a geojson geometry. It reads all OGC
+// Simple Features types, including GEOMETRYCOLLECTION, EMPTY geometries and Z, M and ZM coordinates.
+// EMPTY members of multi geometries and polygons, e.g. MULTIPOINT(EMPTY,(1 2)), are skipped.
+// Identifiers are case insensitive and the coordinates of a geometry must all have the same dimension.
+// GeoJSON has no measures, so the M value of M coordinates is dropped while ZM coordinates keep it as
+// their fourth element. Malformed shapes return a *WKTSyntaxError.
 func WKTToGeometry(wkt string) (g *geojson.Geometry, err error) {
 	return parseWKT([]byte(wkt))
 }
@@ -18,239 +24,640 @@ func parseWKT(data []byte) (g *geojson.Geometry, err error) {
 	return s.scanGeom()
 }
 
+// WKTSyntaxError describes where and why a Well Known Text shape couldn't be read
+type WKTSyntaxError struct {
+	// Offset is the byte offset of the error in the input
+	Offset int
+	// Line and Column are the 1-based position of Offset, columns are counted in bytes
+	Line   int
+	Column int
+	// Expected lists the tokens that were valid at Offset, e.g. "'('", "number" or "EMPTY"
+	Expected []string
+	// Found is the token at Offset, "end of input" when the input ended early
+	Found string
+	// Message explains errors that aren't about an unexpected token, e.g. an unclosed polygon
+	Message string
+	// Snippet is the input around Offset
+	Snippet string
+}
+
+func (e *WKTSyntaxError) Error() string {
+	var msg string
+	if e.Message != "" {
+		msg = e.Message
+	} else {
+		msg = fmt.Sprintf("expect %s got %s", strings.Join(e.Expected, " or "), e.Found)
+	}
+	return fmt.Sprintf("wkt: line %d, column %d: %s near %q", e.Line, e.Column, msg, e.Snippet)
+}
+
+// snippetRadius is the number of bytes either side of an error included in its snippet
+const snippetRadius = 20
+
+// syntaxError creates an error at the given offset, with the token found there
+func (s *scanner) syntaxError(offset int, expected []string, message string) *WKTSyntaxError {
+	lineStart := bytes.LastIndexByte(s.raw[:offset], '\n') + 1
+	lineEnd := len(s.raw)
+	if i := bytes.IndexByte(s.raw[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+	start, end := offset-snippetRadius, offset+snippetRadius
+	if start < lineStart {
+		start = lineStart
+	}
+	if end > lineEnd {
+		end = lineEnd
+	}
+	return &WKTSyntaxError{
+		Offset:   offset,
+		Line:     bytes.Count(s.raw[:offset], []byte{'\n'}) + 1,
+		Column:   offset - lineStart + 1,
+		Expected: expected,
+		Found:    s.token(offset),
+		Message:  message,
+		Snippet:  strings.TrimRight(string(s.raw[start:end]), "\r"),
+	}
+}
+
+// unexpected creates an error for the token at the current position
+func (s *scanner) unexpected(expected ...string) *WKTSyntaxError {
+	return s.syntaxError(s.i, expected, "")
+}
+
+// errorf creates an error with a message at the given offset
+func (s *scanner) errorf(offset int, format string, args ...interface{}) *WKTSyntaxError {
+	return s.syntaxError(offset, nil, fmt.Sprintf(format, args...))
+}
+
+// token describes the token starting at offset for error messages
+func (s *scanner) token(offset int) string {
+	if offset >= len(s.raw) {
+		return "end of input"
+	}
+	end := offset
+	switch b := s.raw[offset]; {
+	case isIdentByte(b):
+		for end < len(s.raw) && isIdentByte(s.raw[end]) {
+			end++
+		}
+	case isNumberStart(b):
+		for end < len(s.raw) && (isNumberStart(s.raw[end]) || s.raw[end] == 'e' || s.raw[end] == 'E') {
+			end++
+		}
+	case b >= ' ' && b <= '~':
+		return fmt.Sprintf("'%c'", b)
+	default:
+		return fmt.Sprintf("byte 0x%02x", b)
+	}
+	return fmt.Sprintf("'%s'", s.raw[offset:end])
+}
+
+// dimension is the coordinate dimension given after a WKT type, e.g. the Z in POINT Z (1 2 3)
+type dimension int
+
+const (
+	dimensionAny dimension = iota
+	dimensionZ
+	dimensionM
+	dimensionZM
+)
+
+// size is the number of values in a coordinate of the dimension, 0 when any size is allowed
+func (d dimension) size() int {
+	switch d {
+	case dimensionZ, dimensionM:
+		return 3
+	case dimensionZM:
+		return 4
+	}
+	return 0
+}
+
+var wktTypes = map[string]geojson.GeometryType{
+	"POINT":              geojson.GeometryPoint,
+	"MULTIPOINT":         geojson.GeometryMultiPoint,
+	"LINESTRING":         geojson.GeometryLineString,
+	"MULTILINESTRING":    geojson.GeometryMultiLineString,
+	"POLYGON":            geojson.GeometryPolygon,
+	"MULTIPOLYGON":       geojson.GeometryMultiPolygon,
+	"GEOMETRYCOLLECTION": geojson.GeometryCollection,
+}
+
+// dimensionTags are the dimensions that can be appended to a geometry type, e.g. POINTZ
+var dimensionTags = []struct {
+	suffix string
+	dim    dimension
+}{
+	{"ZM", dimensionZM},
+	{"Z", dimensionZ},
+	{"M", dimensionM},
+}
+
 type scanner struct {
 	raw []byte
 	i   int
+	// size is the number of values in the coordinates of the geometry being scanned, 0 until known
+	size int
 }
 
+// scanGeom scans a single geometry that must span the whole input
 func (s *scanner) scanGeom() (g *geojson.Geometry, err error) {
-	ident, err := s.scanIndent()
+	g, err = s.scanGeometry()
 	if err != nil {
-		return g, err
+		return nil, err
 	}
-	switch ident {
-	case "POINT", "MULTIPOINT", "LINESTRING":
-		var ps [][]float64
-		ps, err = s.scanPoints(ident == "MULTIPOINT")
-		if err != nil {
-			break
+	s.skipWs()
+	if s.i < len(s.raw) {
+		return nil, s.unexpected("end of input")
+	}
+	return g, nil
+}
+
+func (s *scanner) scanGeometry() (g *geojson.Geometry, err error) {
+	geometryType, dim, err := s.scanType()
+	if err != nil {
+		return nil, err
+	}
+	s.size = dim.size()
+	empty, err := s.scanEmpty()
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return emptyGeometry(geometryType), nil
+	}
+	switch geometryType {
+	case geojson.GeometryPoint:
+		var p []float64
+		if p, err = s.scanPoint(dim); err == nil {
+			g = geojson.NewPointGeometry(p)
 		}
-		switch ident {
-		case "POINT":
-			if len(ps) != 1 {
-				return nil, fmt.Errorf("expected 1 got %d points", len(ps))
-			}
-			g = geojson.NewPointGeometry(ps[0])
-		case "MULTIPOINT":
+	case geojson.GeometryMultiPoint:
+		var ps [][]float64
+		if ps, err = s.scanMultiPoint(dim); err == nil {
 			g = geojson.NewMultiPointGeometry(ps...)
-		case "LINESTRING":
-			g = geojson.NewLineStringGeometry(ps)
 		}
-	case "POLYGON", "MULTILINESTRING":
-		isPolygon := true
-		if ident != "POLYGON" {
-			isPolygon = false
+	case geojson.GeometryLineString:
+		var ls [][]float64
+		start := s.i
+		if ls, err = s.scanLineString(dim); err == nil && len(ls) < 2 {
+			err = s.errorf(start, "a linestring must have at least 2 points, got %d", len(ls))
 		}
-		var polygon [][][]float64
-		polygon, err = s.scanMultiLineString(isPolygon)
-		if err != nil {
-			return nil, err
+		if err == nil {
+			g = geojson.NewLineStringGeometry(ls)
+		}
+	case geojson.GeometryMultiLineString:
+		var mls [][][]float64
+		if mls, err = s.scanMultiLineString(dim, false); err == nil {
+			g = geojson.NewMultiLineStringGeometry(mls...)
 		}
-		if isPolygon {
+	case geojson.GeometryPolygon:
+		var polygon [][][]float64
+		if polygon, err = s.scanMultiLineString(dim, true); err == nil {
 			g = geojson.NewPolygonGeometry(polygon)
-		} else {
-			g = geojson.NewLineStringGeometry(polygon[0])
 		}
-	case "MULTIPOLYGON":
+	case geojson.GeometryMultiPolygon:
 		var multipolygon [][][][]float64
-		multipolygon, err = s.scanMultiPolygon()
-		if err != nil {
-			return nil, err
+		if multipolygon, err = s.scanMultiPolygon(dim); err == nil {
+			g = geojson.NewMultiPolygonGeometry(multipolygon...)
+		}
+	case geojson.GeometryCollection:
+		var geometries []*geojson.Geometry
+		if geometries, err = s.scanCollection(); err == nil {
+			g = geojson.NewCollectionGeometry(geometries...)
 		}
-		g = geojson.NewMultiPolygonGeometry(multipolygon...)
-	default:
-		err = fmt.Errorf("unknown or unimplemented geometry '%s'", ident)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return
+	return g, nil
+}
+
+// emptyGeometry creates a geometry of the given type without coordinates
+func emptyGeometry(geometryType geojson.GeometryType) *geojson.Geometry {
+	switch geometryType {
+	case geojson.GeometryMultiPoint:
+		return geojson.NewMultiPointGeometry([][]float64{}...)
+	case geojson.GeometryLineString:
+		return geojson.NewLineStringGeometry([][]float64{})
+	case geojson.GeometryMultiLineString:
+		return geojson.NewMultiLineStringGeometry([][][]float64{}...)
+	case geojson.GeometryPolygon:
+		return geojson.NewPolygonGeometry([][][]float64{})
+	case geojson.GeometryMultiPolygon:
+		return geojson.NewMultiPolygonGeometry([][][][]float64{}...)
+	case geojson.GeometryCollection:
+		return geojson.NewCollectionGeometry([]*geojson.Geometry{}...)
+	}
+	return &geojson.Geometry{Type: geometryType}
 }
 
-func (s *scanner) scanIndent() (string, error) {
+// scanType scans a geometry type with its optional dimension, e.g. "POINT", "LineString Z" or "POINTZM"
+func (s *scanner) scanType() (geojson.GeometryType, dimension, error) {
 	s.skipWs()
-	var b byte
-	i, start := 0, -1
-	for i, b = range s.raw[s.i:] {
-		if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' {
-			if start < 0 {
-				start = i
+	start := s.i
+	ident, err := s.scanIdent("geometry type")
+	if err != nil {
+		return "", dimensionAny, err
+	}
+	dim := dimensionAny
+	geometryType, known := wktTypes[ident]
+	if !known {
+		for _, tag := range dimensionTags {
+			if t, ok := wktTypes[strings.TrimSuffix(ident, tag.suffix)]; ok && strings.HasSuffix(ident, tag.suffix) {
+				geometryType, dim, known = t, tag.dim, true
+				break
 			}
-			continue
 		}
-		if start < 0 {
-			return "", fmt.Errorf("no ident '%v'", b)
+	}
+	if !known {
+		return "", dimensionAny, s.errorf(start, "unknown or unimplemented geometry '%s'", ident)
+	}
+	if dim != dimensionAny {
+		return geometryType, dim, nil
+	}
+	s.skipWs()
+	if !s.peekIdent() {
+		return geometryType, dim, nil
+	}
+	start = s.i
+	tag, err := s.scanIdent("Z", "M", "ZM")
+	if err != nil {
+		return "", dimensionAny, err
+	}
+	switch tag {
+	case "Z":
+		dim = dimensionZ
+	case "M":
+		dim = dimensionM
+	case "ZM":
+		dim = dimensionZM
+	default:
+		s.i = start
+	}
+	return geometryType, dim, nil
+}
+
+// scanEmpty scans the EMPTY keyword if it's next, or checks the '(' starting the coordinates is next
+// without scanning it. It reports whether the geometry is empty.
+func (s *scanner) scanEmpty() (bool, error) {
+	s.skipWs()
+	if !s.peekIdent() {
+		if s.i < len(s.raw) && s.raw[s.i] == '(' {
+			return false, nil
 		}
-		break
+		return false, s.unexpected("'('", "EMPTY")
+	}
+	if ident, _ := s.scanIdent(); ident != "EMPTY" {
+		s.i -= len(ident)
+		return false, s.unexpected("'('", "EMPTY")
+	}
+	return true, nil
+}
+
+func (s *scanner) peekIdent() bool {
+	return s.i < len(s.raw) && isIdentByte(s.raw[s.i])
+}
+
+func isIdentByte(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z'
+}
+
+// scanIdent scans a word and returns it upper cased. expected names the words that are valid
+// here for the error when there's no word.
+func (s *scanner) scanIdent(expected ...string) (string, error) {
+	s.skipWs()
+	start := s.i
+	for s.peekIdent() {
+		s.i++
+	}
+	if start == s.i {
+		return "", s.unexpected(expected...)
 	}
-	str := string(s.raw[s.i+start : i-start])
-	s.i += i
-	return str, nil
+	return strings.ToUpper(string(s.raw[start:s.i])), nil
 }
 
 func (s *scanner) skipWs() {
-	for i, b := range s.raw[s.i:] {
-		if b == ' ' || b == '\n' || b == '\t' || b == '\r' || b == 65 {
-			continue
+	for s.i < len(s.raw) {
+		switch s.raw[s.i] {
+		case ' ', '\n', '\t', '\r':
+			s.i++
+		default:
+			return
 		}
-		s.i += i
-		return
 	}
 }
 
-func (s *scanner) scanPoints(multi bool) ([][]float64, error) {
-	err := s.scanStart()
+// scanByte scans the given character, after optional whitespace
+func (s *scanner) scanByte(b byte) error {
+	s.skipWs()
+	if s.i >= len(s.raw) || s.raw[s.i] != b {
+		return s.unexpected(fmt.Sprintf("'%c'", b))
+	}
+	s.i++
+	return nil
+}
+
+func (s *scanner) scanStart() error {
+	return s.scanByte('(')
+}
+
+// scanContinue scans the ',' between two elements or the ')' after the last one. It reports
+// whether more elements follow.
+func (s *scanner) scanContinue() (bool, error) {
+	s.skipWs()
+	if s.i >= len(s.raw) || s.raw[s.i] != ',' && s.raw[s.i] != ')' {
+		return false, s.unexpected("','", "')'")
+	}
+	b := s.raw[s.i]
+	s.i++
+	return b == ',', nil
+}
+
+// scanPoint scans the body of a point: a single coordinate between parentheses
+func (s *scanner) scanPoint(dim dimension) ([]float64, error) {
+	if err := s.scanStart(); err != nil {
+		return nil, err
+	}
+	p, err := s.scanCoordinate(dim, nil)
 	if err != nil {
 		return nil, err
 	}
-	var ps [][]float64
-	var p []float64
-	var comma bool
-	if multi {
-		err = s.scanStart()
-		multi = err == nil
+	if err := s.scanByte(')'); err != nil {
+		return nil, err
 	}
+	return p, nil
+}
+
+// scanMultiPoint scans the points of a multipoint, given either as MULTIPOINT((1 2),(3 4)) or MULTIPOINT(1 2,3 4).
+// EMPTY points are skipped.
+func (s *scanner) scanMultiPoint(dim dimension) ([][]float64, error) {
+	if err := s.scanStart(); err != nil {
+		return nil, err
+	}
+	var flat []float64
 	for {
-		p, comma, err = s.scanPoint()
+		var err error
+		s.skipWs()
+		if s.i < len(s.raw) && s.raw[s.i] == '(' {
+			s.i++
+			if flat, err = s.scanCoordinate(dim, flat); err == nil {
+				err = s.scanByte(')')
+			}
+		} else if s.peekIdent() {
+			_, err = s.scanEmpty()
+		} else {
+			flat, err = s.scanCoordinate(dim, flat)
+		}
 		if err != nil {
 			return nil, err
 		}
-		if len(p) < 2 {
-			return nil, fmt.Errorf("point must be at least 2d. got %d elements", len(p))
+		comma, err := s.scanContinue()
+		if err != nil {
+			return nil, err
 		}
-		if len(p) < 2 || len(p) > 4 {
-			return nil, fmt.Errorf("point can be at most 4d. got %d elements", len(p))
+		if !comma {
+			return s.coordinates(flat, dim), nil
 		}
-		ps = append(ps, p)
-		if comma {
-			if multi {
-				return nil, fmt.Errorf("expect ')' got ','")
-			}
-			continue
+	}
+}
+
+// scanLineString scans a list of coordinates between parentheses
+func (s *scanner) scanLineString(dim dimension) ([][]float64, error) {
+	if err := s.scanStart(); err != nil {
+		return nil, err
+	}
+	var flat []float64
+	for {
+		var err error
+		if flat, err = s.scanCoordinate(dim, flat); err != nil {
+			return nil, err
 		}
-		if multi {
-			comma, err = s.scanContinue()
-			if err != nil {
-				return nil, err
-			}
-			if comma {
-				err = s.scanStart()
-				if err != nil {
-					return nil, err
-				}
-				continue
-			}
+		comma, err := s.scanContinue()
+		if err != nil {
+			return nil, err
+		}
+		if !comma {
+			return s.coordinates(flat, dim), nil
 		}
-		return ps, nil
 	}
 }
 
-func (s *scanner) scanStart() error {
-	s.skipWs()
-	start := s.raw[s.i] == '('
-	if !start {
-		return fmt.Errorf("expect '(' got '%v'", s.raw[s.i])
+// coordinates splits the values of consecutive coordinates into a slice per coordinate. The coordinates
+// share the flat slice, which saves an allocation per coordinate.
+func (s *scanner) coordinates(flat []float64, dim dimension) [][]float64 {
+	if len(flat) == 0 {
+		return [][]float64{}
 	}
-	s.i++
-	return nil
+	size := s.size
+	if dim == dimensionM {
+		size = 2
+	}
+	ps := make([][]float64, len(flat)/size)
+	for i := range ps {
+		ps[i] = flat[i*size : (i+1)*size : (i+1)*size]
+	}
+	return ps
 }
 
-func (s *scanner) scanPoint() (p []float64, comma bool, err error) {
-	var pc []float64
+// scanCoordinate scans the numbers of a single coordinate and appends them to flat
+func (s *scanner) scanCoordinate(dim dimension, flat []float64) ([]float64, error) {
 	s.skipWs()
-	r := bytes.NewReader(s.raw[s.i:])
-	var f float64
+	start, n := s.i, len(flat)
 	for {
-		_, err := fmt.Fscan(r, &f)
-		if err != nil {
-			return nil, false, err
-		}
-		pc = append(pc, f)
-		s.i = len(s.raw) - r.Len()
 		s.skipWs()
-		b := s.raw[s.i]
-		if comma = b == ','; comma || b == ')' {
-			s.i++
+		if s.i >= len(s.raw) || !isNumberStart(s.raw[s.i]) {
 			break
 		}
+		f, err := s.scanNumber()
+		if err != nil {
+			return nil, err
+		}
+		flat = append(flat, f)
+	}
+	size := len(flat) - n
+	if size == 0 {
+		return nil, s.unexpected("number")
+	}
+	if size < 2 {
+		return nil, s.errorf(start, "point must be at least 2d. got %d elements", size)
+	}
+	if size > 4 {
+		return nil, s.errorf(start, "point can be at most 4d. got %d elements", size)
+	}
+	if s.size == 0 {
+		s.size = size
+	} else if size != s.size {
+		return nil, s.errorf(start, "point must have %d elements, got %d", s.size, size)
+	}
+	if dim == dimensionM {
+		flat = flat[:n+2]
+	}
+	return flat, nil
+}
+
+func isNumberStart(b byte) bool {
+	return isDigit(b) || b == '-' || b == '+' || b == '.'
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// scanNumber scans a decimal number, e.g. -71.06, .5 or 2.5E-1, which must be followed by whitespace, a
+// ',' or a ')'
+func (s *scanner) scanNumber() (float64, error) {
+	start, i := s.i, s.i
+	if s.raw[i] == '-' || s.raw[i] == '+' {
+		i++
+	}
+	digits := 0
+	for ; i < len(s.raw) && isDigit(s.raw[i]); i++ {
+		digits++
+	}
+	if i < len(s.raw) && s.raw[i] == '.' {
+		for i++; i < len(s.raw) && isDigit(s.raw[i]); i++ {
+			digits++
+		}
 	}
-	return pc, comma, nil
+	valid := digits > 0
+	if valid && i < len(s.raw) && (s.raw[i] == 'e' || s.raw[i] == 'E') {
+		i++
+		if i < len(s.raw) && (s.raw[i] == '-' || s.raw[i] == '+') {
+			i++
+		}
+		exponent := i
+		for ; i < len(s.raw) && isDigit(s.raw[i]); i++ {
+		}
+		valid = i > exponent
+	}
+	if !valid {
+		return 0, s.errorf(start, "invalid number %s", s.token(start))
+	}
+	f, err := strconv.ParseFloat(string(s.raw[start:i]), 64)
+	if err != nil {
+		return 0, s.errorf(start, "invalid number %s", s.token(start))
+	}
+	s.i = i
+	if i < len(s.raw) {
+		switch s.raw[i] {
+		case ' ', '\n', '\t', '\r', ',', ')':
+		default:
+			return 0, s.unexpected("whitespace", "','", "')'")
+		}
+	}
+	return f, nil
 }
 
-func (s *scanner) scanContinue() (bool, error) {
-	s.skipWs()
-	comma := s.raw[s.i] == ','
-	if !comma && s.raw[s.i] != ')' {
-		return comma, fmt.Errorf("expect ',' or ')' got '%v'", s.raw[s.i])
+// equalCoordinates reports whether two coordinates have the same values
+func equalCoordinates(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
 	}
-	s.i++
-	return comma, nil
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
-func (s *scanner) scanMultiLineString(isPolygon bool) ([][][]float64, error) {
-	err := s.scanStart()
-	if err != nil {
+// scanMultiLineString scans a list of linestrings between parentheses. The linestrings of a polygon
+// must be closed rings of at least 4 points. EMPTY linestrings are skipped, but a polygon whose exterior
+// ring is EMPTY can't have interior rings.
+func (s *scanner) scanMultiLineString(dim dimension, isPolygon bool) ([][][]float64, error) {
+	if err := s.scanStart(); err != nil {
+		return nil, err
+	}
+	mls := [][][]float64{}
+	emptyShell := false
+	for first := true; ; first = false {
+		s.skipWs()
+		start := s.i
+		empty, err := s.scanEmpty()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case empty:
+			emptyShell = emptyShell || isPolygon && first
+		case emptyShell:
+			return nil, s.errorf(start, "a polygon with an empty exterior ring can't have interior rings")
+		default:
+			ps, err := s.scanLineString(dim)
+			if err != nil {
+				return nil, err
+			}
+			if isPolygon {
+				if len(ps) < 4 {
+					return nil, s.errorf(start, "a polygon must have at least 4 points, got %d", len(ps))
+				}
+				if !equalCoordinates(ps[0], ps[len(ps)-1]) {
+					return nil, s.errorf(start, "a polygon must be closed")
+				}
+			} else if len(ps) < 2 {
+				return nil, s.errorf(start, "a linestring must have at least 2 points, got %d", len(ps))
+			}
+			mls = append(mls, ps)
+		}
+		comma, err := s.scanContinue()
+		if err != nil {
+			return nil, err
+		}
+		if !comma {
+			return mls, nil
+		}
+	}
+}
+
+// scanMultiPolygon scans a list of polygons between parentheses. EMPTY polygons are skipped.
+func (s *scanner) scanMultiPolygon(dim dimension) ([][][][]float64, error) {
+	if err := s.scanStart(); err != nil {
 		return nil, err
 	}
-	var mls [][][]float64
-	var ps [][]float64
-	var comma bool
+	multi := [][][][]float64{}
 	for {
-		ps, err = s.scanPoints(false)
+		empty, err := s.scanEmpty()
 		if err != nil {
 			return nil, err
 		}
-		if isPolygon {
-			if len(ps) < 4 {
-				return nil, fmt.Errorf("a polygon must have at least 4 points, got %d", len(ps))
+		if !empty {
+			poly, err := s.scanMultiLineString(dim, true)
+			if err != nil {
+				return nil, err
 			}
-			if !reflect.DeepEqual(ps[0], ps[len(ps)-1]) {
-				return nil, fmt.Errorf("a polygon must be closed")
+			if len(poly) > 0 {
+				multi = append(multi, poly)
 			}
 		}
-		mls = append(mls, ps)
-		comma, err = s.scanContinue()
+		comma, err := s.scanContinue()
 		if err != nil {
 			return nil, err
 		}
-		if comma {
-			continue
+		if !comma {
+			return multi, nil
 		}
-		return mls, nil
 	}
 }
 
-func (s *scanner) scanMultiPolygon() ([][][][]float64, error) {
-	err := s.scanStart()
-	if err != nil {
+// scanCollection scans the member geometries of a geometry collection, which may be collections themselves
+func (s *scanner) scanCollection() ([]*geojson.Geometry, error) {
+	if err := s.scanStart(); err != nil {
 		return nil, err
 	}
-	var multi [][][][]float64
-	var poly [][][]float64
-	var comma bool
+	var geometries []*geojson.Geometry
 	for {
-		poly, err = s.scanMultiLineString(true)
+		g, err := s.scanGeometry()
 		if err != nil {
 			return nil, err
 		}
-		multi = append(multi, poly)
-		comma, err = s.scanContinue()
+		geometries = append(geometries, g)
+		comma, err := s.scanContinue()
 		if err != nil {
 			return nil, err
 		}
-		if comma {
-			continue
+		if !comma {
+			return geometries, nil
 		}
-		return multi, nil
 	}
 }
@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+package spatially
+
+import (
+	"testing"
+)
+
+// FuzzWKTToGeometry checks malformed WKT fails with a *WKTSyntaxError instead of panicking, and that
+// shapes which are read survive a round trip through GeometryToWKT
+func FuzzWKTToGeometry(f *testing.F) {
+	for _, test := range wktConformance {
+		f.Add(test.wkt)
+	}
+	for _, wkt := range []string{"POINT(1 2", "POLYGON((0 0,1 0,1 1,0 1))", "GEOMETRYCOLLECTION(POINT(1 2),)", "POINT(1e 2)", "POINT(1e999 2)"} {
+		f.Add(wkt)
+	}
+	f.Fuzz(func(t *testing.T, wkt string) {
+		g, err := WKTToGeometry(wkt)
+		if err != nil {
+			if _, ok := err.(*WKTSyntaxError); !ok {
+				t.Fatalf("Expected a *WKTSyntaxError for %q, got %#v", wkt, err)
+			}
+			return
+		}
+		written, err := GeometryToWKT(g)
+		if err != nil {
+			t.Fatalf("Can't write %q: %s", wkt, err)
+		}
+		if _, err := WKTToGeometry(written); err != nil {
+			t.Fatalf("Can't read %q written for %q: %s", written, wkt, err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package spatially
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/pkg/errors"
+)
+
+// Column names with a special meaning in WKTReaderOptions.Columns
+const (
+	// WKTColumnGeometry is the column holding the WKT or EWKT shape
+	WKTColumnGeometry = "$geometry"
+	// WKTColumnID is the column holding the feature ID
+	WKTColumnID = "$id"
+)
+
+// WKTReaderOptions describes the lines read by a WKTReader
+type WKTReaderOptions struct {
+	// Delimiter separates the columns of a line, a tab by default. It must be a valid character that
+	// can't occur in the shapes, so a comma or a space won't do.
+	Delimiter rune
+	// Columns names the columns of a line in order. Other than WKTColumnGeometry and WKTColumnID,
+	// named columns are read as string properties and columns named "" are skipped, as are columns
+	// past the last name. By default a line is a single geometry column.
+	Columns []string
+	// Header skips the first line
+	Header bool
+}
+
+// WKTReader reads features from text with a shape per line, one line at a time, so files of any size
+// can be streamed into a layer. Blank lines are skipped.
+type WKTReader struct {
+	r        *bufio.Reader
+	options  WKTReaderOptions
+	geometry int
+	line     int
+}
+
+// NewWKTReader creates a reader of the lines of r. Options may be nil to read a shape per line.
+func NewWKTReader(r io.Reader, options *WKTReaderOptions) *WKTReader {
+	reader := &WKTReader{
+		r: bufio.NewReader(r),
+	}
+	if options != nil {
+		reader.options = *options
+	}
+	if reader.options.Delimiter == 0 {
+		reader.options.Delimiter = '\t'
+	}
+	if len(reader.options.Columns) == 0 {
+		reader.options.Columns = []string{WKTColumnGeometry}
+	}
+	reader.geometry = -1
+	for i, column := range reader.options.Columns {
+		if column == WKTColumnGeometry {
+			reader.geometry = i
+		}
+	}
+	return reader
+}
+
+// Read reads the feature of the next line. Shapes with an SRID are reprojected like NewFeatureFromEWKT.
+// Errors name the line they were found on. At the end of the input Read returns a nil feature and io.EOF.
+func (r *WKTReader) Read() (feature *Feature, err error) {
+	if r.geometry < 0 {
+		return nil, fmt.Errorf("wkt reader has no %s column", WKTColumnGeometry)
+	}
+	if !utf8.ValidRune(r.options.Delimiter) {
+		return nil, fmt.Errorf("wkt reader delimiter %d isn't a valid character", r.options.Delimiter)
+	}
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	feature = &Feature{
+		Feature: &geojson.Feature{},
+	}
+	for i, column := range r.options.Columns {
+		if line == nil {
+			return nil, fmt.Errorf("wkt reader line %d has %d columns, expected %d", r.line, i, len(r.options.Columns))
+		}
+		value := line
+		if end := bytes.IndexRune(line, r.options.Delimiter); end >= 0 {
+			value, line = line[:end], line[end+utf8.RuneLen(r.options.Delimiter):]
+		} else {
+			line = nil
+		}
+		switch column {
+		case "":
+		case WKTColumnGeometry:
+			f, err := NewFeatureFromEWKT(string(value))
+			if err != nil {
+				return nil, errors.Wrapf(err, "wkt reader line %d", r.line)
+			}
+			feature.Geometry = f.Geometry
+		case WKTColumnID:
+			feature.ID = string(value)
+		default:
+			if feature.Properties == nil {
+				feature.Properties = map[string]interface{}{}
+			}
+			feature.Properties[column] = string(value)
+		}
+	}
+	return feature, nil
+}
+
+// readLine reads the next line that isn't blank, without its line ending
+func (r *WKTReader) readLine() ([]byte, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		r.line++
+		if r.line == 1 && r.options.Header {
+			continue
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package spatially
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+func TestWKTReader(t *testing.T) {
+	input := "id\tshape\tname\tignored\n" +
+		"1\tPOINT(1 2)\tStarbucks\tx\r\n" +
+		"\n" +
+		"2\tLINESTRING(1 2,3 4)\tDunkin'\ty\n" +
+		"3\tSRID=3857;POINT(0 0)\t\tz"
+	r := NewWKTReader(strings.NewReader(input), &WKTReaderOptions{
+		Columns: []string{WKTColumnID, WKTColumnGeometry, "name"},
+		Header:  true,
+	})
+	var features []*Feature
+	for {
+		feature, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		features = append(features, feature)
+	}
+	if len(features) != 3 {
+		t.Fatal("Expected 3 features, got", len(features))
+	}
+	if features[0].ID != "1" || features[0].Properties["name"] != "Starbucks" || features[0].Geometry.Point[1] != 2 {
+		t.Errorf("Unexpected first feature %+v", features[0].Feature)
+	}
+	if features[1].ID != "2" || features[1].Properties["name"] != "Dunkin'" || len(features[1].Geometry.LineString) != 2 {
+		t.Errorf("Unexpected second feature %+v", features[1].Feature)
+	}
+	if p := features[2].Geometry.Point; math.Abs(p[0]) > 1e-9 || math.Abs(p[1]) > 1e-9 || features[2].Properties["name"] != "" {
+		t.Errorf("Unexpected third feature %+v", features[2].Feature)
+	}
+}
+
+func TestWKTReaderDefaults(t *testing.T) {
+	r := NewWKTReader(strings.NewReader("POINT(1 2)\nPOINT(3 4)\n"), nil)
+	for _, x := range []float64{1, 3} {
+		feature, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feature.Geometry.Point[0] != x || feature.Properties != nil {
+			t.Errorf("Unexpected feature %+v", feature.Feature)
+		}
+	}
+	if feature, err := r.Read(); err != io.EOF || feature != nil {
+		t.Error("Expected io.EOF, got", feature, err)
+	}
+}
+
+func TestWKTReaderDelimiter(t *testing.T) {
+	r := NewWKTReader(strings.NewReader("a→POINT(1 2)\n"), &WKTReaderOptions{
+		Delimiter: '→',
+		Columns:   []string{"name", WKTColumnGeometry},
+	})
+	feature, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feature.Properties["name"] != "a" || feature.Geometry.Point[0] != 1 {
+		t.Errorf("Unexpected feature %+v", feature.Feature)
+	}
+}
+
+func TestWKTReaderInvalidDelimiter(t *testing.T) {
+	for _, delimiter := range []rune{-1, utf8.MaxRune + 1, 0xD800} {
+		r := NewWKTReader(strings.NewReader("a\uFFFDPOINT(1 2)\n"), &WKTReaderOptions{
+			Delimiter: delimiter,
+			Columns:   []string{"name", WKTColumnGeometry},
+		})
+		if feature, err := r.Read(); err == nil {
+			t.Errorf("Expected an error for delimiter %d, got %+v", delimiter, feature)
+		}
+	}
+}
+
+func TestWKTReaderErrors(t *testing.T) {
+	r := NewWKTReader(strings.NewReader("1\tPOINT(1 2)\n2\tPOINT(1\n3\n"), &WKTReaderOptions{
+		Columns: []string{WKTColumnID, WKTColumnGeometry},
+	})
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Read()
+	if _, ok := errors.Cause(err).(*WKTSyntaxError); !ok || !strings.Contains(err.Error(), "line 2") {
+		t.Error("Expected a syntax error on line 2, got", err)
+	}
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "line 3 has 1 columns") {
+		t.Error("Expected a missing column error on line 3, got", err)
+	}
+
+	r = NewWKTReader(strings.NewReader("POINT(1 2)\n"), &WKTReaderOptions{Columns: []string{"name"}})
+	if _, err := r.Read(); err == nil {
+		t.Error("Expected an error without geometry column")
+	}
+}
@@ -0,0 +1,221 @@
+package spatially
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// wktConformance are WKT shapes and the GeoJSON geometries they are read as
+var wktConformance = []struct {
+	wkt     string
+	geojson string
+}{
+	{"POINT(1 2)", `{"type":"Point","coordinates":[1,2]}`},
+	{"POINT (1 2)", `{"type":"Point","coordinates":[1,2]}`},
+	{"point(1 2)", `{"type":"Point","coordinates":[1,2]}`},
+	{"Point ( -71.064156780428   42.35862883483673 )", `{"type":"Point","coordinates":[-71.064156780428,42.35862883483673]}`},
+	{"\tPOINT\n(1e3 -2.5E-1)\r\n", `{"type":"Point","coordinates":[1000,-0.25]}`},
+	{"POINT Z (1 2 3)", `{"type":"Point","coordinates":[1,2,3]}`},
+	{"POINTZ(1 2 3)", `{"type":"Point","coordinates":[1,2,3]}`},
+	{"POINT M (1 2 3)", `{"type":"Point","coordinates":[1,2]}`},
+	{"POINT ZM (1 2 3 4)", `{"type":"Point","coordinates":[1,2,3,4]}`},
+	{"pointzm(1 2 3 4)", `{"type":"Point","coordinates":[1,2,3,4]}`},
+	{"POINT(1 2 3)", `{"type":"Point","coordinates":[1,2,3]}`},
+	{"POINT EMPTY", `{"type":"Point","coordinates":null}`},
+	{"POINT Z EMPTY", `{"type":"Point","coordinates":null}`},
+	{"MULTIPOINT((1 2),(3 4))", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+	{"MULTIPOINT(1 2, 3 4)", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+	{"MULTIPOINT Z ((1 2 3), (4 5 6))", `{"type":"MultiPoint","coordinates":[[1,2,3],[4,5,6]]}`},
+	{"MULTIPOINT EMPTY", `{"type":"MultiPoint","coordinates":[]}`},
+	{"MULTIPOINT(EMPTY,(1 2))", `{"type":"MultiPoint","coordinates":[[1,2]]}`},
+	{"MULTIPOINT Z ((1 2 3),empty)", `{"type":"MultiPoint","coordinates":[[1,2,3]]}`},
+	{"MULTIPOINT(EMPTY)", `{"type":"MultiPoint","coordinates":[]}`},
+	{"LINESTRING(1 2,3 4)", `{"type":"LineString","coordinates":[[1,2],[3,4]]}`},
+	{"LineString M (1 2 0, 3 4 1)", `{"type":"LineString","coordinates":[[1,2],[3,4]]}`},
+	{"LINESTRING EMPTY", `{"type":"LineString","coordinates":[]}`},
+	{"MULTILINESTRING((1 2,3 4),(5 6,7 8))", `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]],[[5,6],[7,8]]]}`},
+	{"MULTILINESTRING EMPTY", `{"type":"MultiLineString","coordinates":[]}`},
+	{"MULTILINESTRING((1 2,3 4),EMPTY)", `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]]]}`},
+	{"POLYGON((0 0,1 0,1 1,0 0))", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`},
+	{"POLYGON((0 0,10 0,10 10,0 10,0 0),(1 1,2 1,2 2,1 1))", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[1,1],[2,1],[2,2],[1,1]]]}`},
+	{"POLYGON Z ((0 0 1,1 0 1,1 1 1,0 0 1))", `{"type":"Polygon","coordinates":[[[0,0,1],[1,0,1],[1,1,1],[0,0,1]]]}`},
+	{"POLYGON EMPTY", `{"type":"Polygon","coordinates":[]}`},
+	{"POLYGON(EMPTY)", `{"type":"Polygon","coordinates":[]}`},
+	{"POLYGON((0 0,1 0,1 1,0 0),EMPTY)", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`},
+	{"MULTIPOLYGON(((0 0,1 0,1 1,0 0)),((5 5,6 5,6 6,5 5)))", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}`},
+	{"MULTIPOLYGON EMPTY", `{"type":"MultiPolygon","coordinates":[]}`},
+	{"MULTIPOLYGON(((0 0,1 0,1 1,0 0)),EMPTY)", `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`},
+	{"MULTIPOLYGON(EMPTY,(EMPTY),((5 5,6 5,6 6,5 5)))", `{"type":"MultiPolygon","coordinates":[[[[5,5],[6,5],[6,6],[5,5]]]]}`},
+	{"GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(1 2,3 4))", `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`},
+	{"GEOMETRYCOLLECTION(POINT(1 2),GEOMETRYCOLLECTION(POINT EMPTY,POLYGON((0 0,1 0,1 1,0 0))))", `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":null},{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}]}]}`},
+	{"GEOMETRYCOLLECTION EMPTY", `{"type":"GeometryCollection","geometries":[]}`},
+}
+
+func TestWKTToGeometryConformance(t *testing.T) {
+	for _, test := range wktConformance {
+		g, err := WKTToGeometry(test.wkt)
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		j, err := json.Marshal(g)
+		if err != nil {
+			t.Error(test.wkt, err)
+			continue
+		}
+		if canonicalJSON(t, j) != canonicalJSON(t, []byte(test.geojson)) {
+			t.Error(test.wkt, "expected", test.geojson, "got", string(j))
+		}
+	}
+}
+
+// canonicalJSON re-encodes a JSON document so documents that only differ in key order are equal
+func canonicalJSON(t *testing.T, j []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(j, &v); err != nil {
+		t.Fatal(string(j), err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(canonical)
+}
+
+func TestWKTToGeometryInvalid(t *testing.T) {
+	for _, wkt := range []string{
+		"",
+		"   ",
+		"CIRCLE(1 2)",
+		"POINT",
+		"POINT(",
+		"POINT(1 2",
+		"POINT(1)",
+		"POINT(1 2 3 4 5)",
+		"POINT Z (1 2)",
+		"POINT ZM (1 2 3)",
+		"POINT(1 2,3 4)",
+		"POINT(1 2) POINT(3 4)",
+		"POINT FULL",
+		"POINT(a b)",
+		"POINT(1e999 2)",
+		"POINT(1e 2)",
+		"POINT(- 2)",
+		"POINT(1 2-3)",
+		"POINT(1.2.3 4)",
+		"MULTIPOINT((1 2),(3 4)",
+		"MULTIPOINT(FULL,(1 2))",
+		"MULTILINESTRING((1 2,3 4),FULL)",
+		"POLYGON(EMPTY,(0 0,1 0,1 1,0 0))",
+		"MULTIPOLYGON(((0 0,1 0,1 1,0 0)),EMPTY,)",
+		"LINESTRING(1 2,)",
+		"LINESTRING(1 2 3 4)",
+		"LINESTRING(1 2,3 4 5)",
+		"MULTILINESTRING((0 0 0,0 0))",
+		"POLYGON((0 0,1 0,1 1,0 0)",
+		"POLYGON((0 0,1 0,1 1,0 1))",
+		"POLYGON((0 0,1 0,0 0))",
+		"MULTIPOLYGON((0 0,1 0,1 1,0 0))",
+		"GEOMETRYCOLLECTION(POINT(1 2),)",
+		"GEOMETRYCOLLECTION(1 2)",
+	} {
+		if g, err := WKTToGeometry(wkt); err == nil {
+			t.Errorf("Expected an error for %q, got %+v", wkt, g)
+		}
+	}
+}
+
+func TestWKTSyntaxError(t *testing.T) {
+	for _, test := range []struct {
+		wkt      string
+		offset   int
+		line     int
+		column   int
+		expected []string
+		found    string
+	}{
+		{"POINT(1 2", 9, 1, 10, []string{"')'"}, "end of input"},
+		{"POINTP(1 2)", 0, 1, 1, nil, "'POINTP'"},
+		{"POINT[1 2]", 5, 1, 6, []string{"'('", "EMPTY"}, "'['"},
+		{"POINT FULL", 6, 1, 7, []string{"'('", "EMPTY"}, "'FULL'"},
+		{"LINESTRING(1 2;3 4)", 14, 1, 15, []string{"whitespace", "','", "')'"}, "';'"},
+		{"LINESTRING(1 2,)", 15, 1, 16, []string{"number"}, "')'"},
+		{"POINT(1 2) POINT(3 4)", 11, 1, 12, []string{"end of input"}, "'POINT'"},
+		{"MULTIPOLYGON(\n  ((0 0,1 0,1 1,0 0)),\n  ((5 5,6 5,6 6,5 5))\n  x)", 61, 4, 3, []string{"','", "')'"}, "'x'"},
+		{"POLYGON((0 0,1 0,1 1,0 1))", 8, 1, 9, nil, "'('"},
+		{"POINT(1 2\xff)", 9, 1, 10, []string{"whitespace", "','", "')'"}, "byte 0xff"},
+		{"POINT(1 2-3)", 9, 1, 10, []string{"whitespace", "','", "')'"}, "'-3'"},
+		{"POINT(1.2.3 4)", 9, 1, 10, []string{"whitespace", "','", "')'"}, "'.3'"},
+	} {
+		_, err := WKTToGeometry(test.wkt)
+		syntaxErr, ok := err.(*WKTSyntaxError)
+		if !ok {
+			t.Errorf("Expected a *WKTSyntaxError for %q, got %#v", test.wkt, err)
+			continue
+		}
+		if syntaxErr.Offset != test.offset || syntaxErr.Line != test.line || syntaxErr.Column != test.column {
+			t.Errorf("Expected %q to fail at offset %d (%d:%d), got %d (%d:%d): %s", test.wkt, test.offset,
+				test.line, test.column, syntaxErr.Offset, syntaxErr.Line, syntaxErr.Column, err)
+		}
+		if !reflect.DeepEqual(syntaxErr.Expected, test.expected) || syntaxErr.Found != test.found {
+			t.Errorf("Expected %q to expect %v and find %s, got %v and %s", test.wkt, test.expected, test.found,
+				syntaxErr.Expected, syntaxErr.Found)
+		}
+		if (test.expected == nil) == (syntaxErr.Message == "") {
+			t.Errorf("Expected a message only for errors without expected tokens, got %q for %q", syntaxErr.Message, test.wkt)
+		}
+	}
+}
+
+func TestWKTSyntaxErrorMessage(t *testing.T) {
+	_, err := WKTToGeometry("POINT(1 2")
+	if err == nil || err.Error() != `wkt: line 1, column 10: expect ')' got end of input near "POINT(1 2"` {
+		t.Error("Unexpected error", err)
+	}
+	_, _, err = EWKTToGeometry("SRID=4326;POINT(1 2")
+	if syntaxErr, ok := err.(*WKTSyntaxError); !ok || syntaxErr.Offset != 19 {
+		t.Errorf("Expected the EWKT error at offset 19, got %#v", err)
+	}
+}
+
+// benchmarkPolygon is a closed ring of n vertices around a center, with coordinates as precise as an ATA's
+func benchmarkPolygon(lon, lat float64, n int) [][][]float64 {
+	ring := make([][]float64, n+1)
+	for i := range ring[:n] {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		ring[i] = []float64{lon + 0.1*math.Cos(angle) + 1e-9*float64(i), lat + 0.1*math.Sin(angle)}
+	}
+	ring[n] = ring[0]
+	return [][][]float64{ring}
+}
+
+func benchmarkWKTToGeometry(b *testing.B, g *geojson.Geometry) {
+	wkt, err := GeometryToWKT(g)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(wkt)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := WKTToGeometry(wkt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWKTToGeometryPolygon(b *testing.B) {
+	benchmarkWKTToGeometry(b, geojson.NewPolygonGeometry(benchmarkPolygon(-71.06, 42.35, 50000)))
+}
+
+func BenchmarkWKTToGeometryMultiPolygon(b *testing.B) {
+	polygons := make([][][][]float64, 100)
+	for i := range polygons {
+		polygons[i] = benchmarkPolygon(-71.06+float64(i), 42.35, 1000)
+	}
+	benchmarkWKTToGeometry(b, geojson.NewMultiPolygonGeometry(polygons...))
+}
@@ -0,0 +1,201 @@
+package spatially
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// GeometryToWKT converts a geojson geometry into a Well Known Text shape. Coordinates are written
+// with as many decimals as needed to read them back exactly.
+func GeometryToWKT(g *geojson.Geometry) (string, error) {
+	return GeometryToWKTPrecision(g, -1)
+}
+
+// GeometryToWKTPrecision converts a geojson geometry into a Well Known Text shape, rounding coordinates
+// to the given number of decimals. Trailing zeros are dropped. A negative precision behaves like GeometryToWKT.
+func GeometryToWKTPrecision(g *geojson.Geometry, precision int) (string, error) {
+	w := &wktWriter{precision: precision}
+	if err := w.writeGeometry(g); err != nil {
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+type wktWriter struct {
+	buf       bytes.Buffer
+	precision int
+}
+
+func (w *wktWriter) writeGeometry(g *geojson.Geometry) error {
+	if g == nil {
+		return fmt.Errorf("nil geometry")
+	}
+	switch g.Type {
+	case geojson.GeometryPoint:
+		w.buf.WriteString("POINT")
+		w.writeDimension(g)
+		if len(g.Point) == 0 {
+			w.buf.WriteString(" EMPTY")
+			return nil
+		}
+		w.buf.WriteByte('(')
+		if err := w.writeCoordinate(g.Point); err != nil {
+			return err
+		}
+		w.buf.WriteByte(')')
+	case geojson.GeometryMultiPoint:
+		w.buf.WriteString("MULTIPOINT")
+		w.writeDimension(g)
+		if len(g.MultiPoint) == 0 {
+			w.buf.WriteString(" EMPTY")
+			return nil
+		}
+		w.buf.WriteByte('(')
+		for i, p := range g.MultiPoint {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			w.buf.WriteByte('(')
+			if err := w.writeCoordinate(p); err != nil {
+				return err
+			}
+			w.buf.WriteByte(')')
+		}
+		w.buf.WriteByte(')')
+	case geojson.GeometryLineString:
+		w.buf.WriteString("LINESTRING")
+		w.writeDimension(g)
+		return w.writeLine(g.LineString)
+	case geojson.GeometryMultiLineString:
+		w.buf.WriteString("MULTILINESTRING")
+		w.writeDimension(g)
+		return w.writeLines(g.MultiLineString)
+	case geojson.GeometryPolygon:
+		w.buf.WriteString("POLYGON")
+		w.writeDimension(g)
+		return w.writeLines(g.Polygon)
+	case geojson.GeometryMultiPolygon:
+		w.buf.WriteString("MULTIPOLYGON")
+		w.writeDimension(g)
+		if len(g.MultiPolygon) == 0 {
+			w.buf.WriteString(" EMPTY")
+			return nil
+		}
+		w.buf.WriteByte('(')
+		for i, polygon := range g.MultiPolygon {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.writeLines(polygon); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(')')
+	case geojson.GeometryCollection:
+		w.buf.WriteString("GEOMETRYCOLLECTION")
+		if len(g.Geometries) == 0 {
+			w.buf.WriteString(" EMPTY")
+			return nil
+		}
+		w.buf.WriteByte('(')
+		for i, geometry := range g.Geometries {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.writeGeometry(geometry); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(')')
+	default:
+		return fmt.Errorf("unknown or unimplemented geometry '%s'", g.Type)
+	}
+	return nil
+}
+
+// writeDimension writes the Z tag of geometries with 3d coordinates and the ZM tag of those with 4d
+// coordinates. The size of the first coordinate decides.
+func (w *wktWriter) writeDimension(g *geojson.Geometry) {
+	switch coordinateSize(g) {
+	case 3:
+		w.buf.WriteString(" Z ")
+	case 4:
+		w.buf.WriteString(" ZM ")
+	}
+}
+
+func (w *wktWriter) writeLines(lines [][][]float64) error {
+	if len(lines) == 0 {
+		w.buf.WriteString(" EMPTY")
+		return nil
+	}
+	w.buf.WriteByte('(')
+	for i, line := range lines {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		if err := w.writeLine(line); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte(')')
+	return nil
+}
+
+func (w *wktWriter) writeLine(line [][]float64) error {
+	if len(line) == 0 {
+		w.buf.WriteString(" EMPTY")
+		return nil
+	}
+	w.buf.WriteByte('(')
+	for i, p := range line {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		if err := w.writeCoordinate(p); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte(')')
+	return nil
+}
+
+func (w *wktWriter) writeCoordinate(p []float64) error {
+	if len(p) < 2 || len(p) > 4 {
+		return fmt.Errorf("point must have 2 to 4 elements, got %d", len(p))
+	}
+	for i, f := range p {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("invalid coordinate %v", f)
+		}
+		if i > 0 {
+			w.buf.WriteByte(' ')
+		}
+		w.writeFloat(f)
+	}
+	return nil
+}
+
+func (w *wktWriter) writeFloat(f float64) {
+	if w.precision < 0 {
+		w.buf.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		return
+	}
+	s := strconv.FormatFloat(f, 'f', w.precision, 64)
+	if w.precision > 0 {
+		for s[len(s)-1] == '0' {
+			s = s[:len(s)-1]
+		}
+		if s[len(s)-1] == '.' {
+			s = s[:len(s)-1]
+		}
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	w.buf.WriteString(s)
+}
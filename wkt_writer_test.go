@@ -0,0 +1,108 @@
+package spatially
+
+import (
+	"reflect"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestGeometryToWKT(t *testing.T) {
+	for _, test := range []struct {
+		geometry *geojson.Geometry
+		wkt      string
+	}{
+		{geojson.NewPointGeometry([]float64{-71.064156780428, 42.35862883483673}), "POINT(-71.064156780428 42.35862883483673)"},
+		{geojson.NewPointGeometry([]float64{1, 2, 3}), "POINT Z (1 2 3)"},
+		{geojson.NewPointGeometry([]float64{1, 2, 3, 4}), "POINT ZM (1 2 3 4)"},
+		{geojson.NewMultiPointGeometry([]float64{1, 2}, []float64{3, 4}), "MULTIPOINT((1 2),(3 4))"},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 0, 1}}}), "POLYGON Z ((0 0 1,1 0 1,1 1 1,0 0 1))"},
+		{geojson.NewLineStringGeometry([][]float64{{1, 2}, {3, 4}, {5, 6}}), "LINESTRING(1 2,3 4,5 6)"},
+		{geojson.NewMultiLineStringGeometry([][]float64{{1, 2}, {3, 4}}, [][]float64{{5, 6}, {7, 8}}), "MULTILINESTRING((1 2,3 4),(5 6,7 8))"},
+		{geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}, {{1, 1}, {2, 1}, {2, 2}, {1, 1}}}), "POLYGON((0 0,10 0,10 10,0 0),(1 1,2 1,2 2,1 1))"},
+		{geojson.NewMultiPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, [][][]float64{{{5, 5}, {6, 5}, {6, 6}, {5, 5}}}), "MULTIPOLYGON(((0 0,1 0,1 1,0 0)),((5 5,6 5,6 6,5 5)))"},
+		{geojson.NewCollectionGeometry(geojson.NewPointGeometry([]float64{1, 2}), geojson.NewLineStringGeometry([][]float64{{1, 2}, {3, 4}})), "GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(1 2,3 4))"},
+		{geojson.NewCollectionGeometry(), "GEOMETRYCOLLECTION EMPTY"},
+		{&geojson.Geometry{Type: geojson.GeometryPoint}, "POINT EMPTY"},
+		{geojson.NewPolygonGeometry(nil), "POLYGON EMPTY"},
+	} {
+		wkt, err := GeometryToWKT(test.geometry)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if wkt != test.wkt {
+			t.Error("Expected", test.wkt, "got", wkt)
+		}
+	}
+}
+
+func TestGeometryToWKTPrecision(t *testing.T) {
+	g := geojson.NewLineStringGeometry([][]float64{{-71.064156780428, 42.35862883483673}, {-71.5, 42}, {-0.0000001, 0.1}})
+	for precision, expected := range map[int]string{
+		0: "LINESTRING(-71 42,-72 42,0 0)",
+		2: "LINESTRING(-71.06 42.36,-71.5 42,0 0.1)",
+		6: "LINESTRING(-71.064157 42.358629,-71.5 42,0 0.1)",
+	} {
+		wkt, err := GeometryToWKTPrecision(g, precision)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if wkt != expected {
+			t.Error("Precision", precision, "expected", expected, "got", wkt)
+		}
+	}
+}
+
+func TestGeometryToWKTErrors(t *testing.T) {
+	for _, g := range []*geojson.Geometry{
+		nil,
+		geojson.NewPointGeometry([]float64{1}),
+		geojson.NewLineStringGeometry([][]float64{{1, 2, 3, 4, 5}}),
+		{Type: "Circle"},
+		geojson.NewCollectionGeometry(geojson.NewPointGeometry([]float64{1})),
+	} {
+		if wkt, err := GeometryToWKT(g); err == nil {
+			t.Error("Expected an error, got", wkt)
+		}
+	}
+}
+
+func TestGeometryToWKTRoundTrip(t *testing.T) {
+	for _, wkt := range []string{
+		"POINT(-71.064156780428 42.35862883483673)",
+		"MULTIPOINT((-71.06 42.35),(-71.07 42.36))",
+		"LINESTRING(-71.06 42.35,-71.07 42.36,-71.08 42.37)",
+		"POLYGON((-71.06296062469482 42.362336359418954,-71.05918407440186 42.358277337975814,-71.06665134429932 42.35979950174449,-71.06296062469482 42.362336359418954))",
+		"POLYGON((0 0,10 0,10 10,0 10,0 0),(1 1,2 1,2 2,1 2,1 1))",
+		"MULTIPOLYGON(((0 0,1 0,1 1,0 0)),((5 5,6 5,6 6,5 5),(5.1 5.1,5.2 5.1,5.2 5.2,5.1 5.1)))",
+		"MULTILINESTRING((1 2,3 4),(5 6,7 8))",
+		"LINESTRING Z (1 2 3,4 5 6)",
+		"POINT ZM (1 2 3 4)",
+		"GEOMETRYCOLLECTION(POINT(1 2),GEOMETRYCOLLECTION(LINESTRING(1 2,3 4),POLYGON EMPTY))",
+		"POINT EMPTY",
+	} {
+		g, err := WKTToGeometry(wkt)
+		if err != nil {
+			t.Error(wkt, err)
+			continue
+		}
+		written, err := GeometryToWKT(g)
+		if err != nil {
+			t.Error(wkt, err)
+			continue
+		}
+		if written != wkt {
+			t.Error("Expected", wkt, "got", written)
+		}
+		parsed, err := WKTToGeometry(written)
+		if err != nil {
+			t.Error(written, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, g) {
+			t.Error("Round trip of", wkt, "does not match")
+		}
+	}
+}